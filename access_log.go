@@ -0,0 +1,375 @@
+package echolog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trafficstars/echo"
+	"github.com/trafficstars/echo/engine"
+	"github.com/trafficstars/echo/engine/fasthttp"
+	"github.com/trafficstars/echo/engine/standard"
+	"github.com/valyala/fasttemplate"
+)
+
+// AccessLogFormat defines the format of the access log
+type AccessLogFormat int
+
+const (
+	// AccessLogFormatStructured writes the access log through the logger (with fields)
+	AccessLogFormatStructured AccessLogFormat = iota
+
+	// AccessLogFormatCommon writes the access log in NCSA Common Log Format
+	// to AccessLogOptions.Writer
+	AccessLogFormatCommon
+
+	// AccessLogFormatCombined writes the access log in NCSA Combined Log Format
+	// (Apache "combined") to AccessLogOptions.Writer
+	AccessLogFormatCombined
+
+	// AccessLogFormatTemplate writes the access log using AccessLogOptions.Template
+	// to AccessLogOptions.Writer
+	AccessLogFormatTemplate
+)
+
+const (
+	// AccessLogTemplateCommon is the template of NCSA Common Log Format
+	AccessLogTemplateCommon = `${remote_ip} - ${remote_user} [${time_common}] "${method} ${uri} ${protocol}" ${status} ${bytes_out_clf}`
+
+	// AccessLogTemplateCombined is the template of NCSA Combined Log Format
+	AccessLogTemplateCombined = AccessLogTemplateCommon + ` "${referer}" "${user_agent}"`
+
+	accessLogTimeCommonLayout = `02/Jan/2006:15:04:05 -0700`
+)
+
+// AccessLogOptions configures the access log: a single line written at the end of each request
+//
+// Variables supported in Template: ${remote_ip}, ${remote_user}, ${method}, ${uri},
+// ${path}, ${route}, ${query}, ${protocol}, ${host}, ${status}, ${latency},
+// ${latency_ms}, ${latency_us}, ${bytes_in}, ${bytes_out}, ${bytes_out_clf},
+// ${request_id}, ${user_agent}, ${referer}, ${time_rfc3339}, ${time_common},
+// ${error}, ${server_timing}. Empty values are written as "-".
+type AccessLogOptions struct {
+	Disable  bool            // Do not write the access log
+	Format   AccessLogFormat // The format of the access log (structured through the logger by default)
+	Template string          // The template for AccessLogFormatTemplate, for example: "${remote_ip} ${method} ${uri} ${status} ${latency_ms} ${request_id}"
+	Writer   io.Writer       // Where to write non-structured access logs (os.Stdout by default)
+}
+
+type accessLogger struct {
+	template *fasttemplate.Template
+
+	writerLock sync.Mutex
+	writer     io.Writer
+	buffer     bytes.Buffer
+}
+
+func newAccessLogger(opts AccessLogOptions, sink Sink) *accessLogger {
+	if opts.Disable {
+		return nil
+	}
+
+	var template string
+	switch opts.Format {
+	case AccessLogFormatStructured:
+		return &accessLogger{}
+	case AccessLogFormatCommon:
+		template = AccessLogTemplateCommon
+	case AccessLogFormatCombined:
+		template = AccessLogTemplateCombined
+	case AccessLogFormatTemplate:
+		template = opts.Template
+	default:
+		writeSinkf(sink, SinkLevelError, `Unknown access log format: %v`, opts.Format)
+		return &accessLogger{}
+	}
+
+	compiledTemplate, err := fasttemplate.NewTemplate(template, `${`, `}`)
+	if err != nil {
+		writeSinkf(sink, SinkLevelError, `Invalid access log template "%v": %v`, template, err)
+		return &accessLogger{}
+	}
+
+	// Check that all variables of the template are known
+	var emptyEntry accessLogEntry
+	compiledTemplate.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		if _, ok := emptyEntry.variable(tag); !ok {
+			writeSinkf(sink, SinkLevelError, `Unknown variable "${%v}" in the access log template`, tag)
+		}
+		return 0, nil
+	})
+
+	l := &accessLogger{
+		template: compiledTemplate,
+		writer:   opts.Writer,
+	}
+	if l.writer == nil {
+		l.writer = os.Stdout
+	}
+	return l
+}
+
+// accessLogEntry is a summary of a finished request
+type accessLogEntry struct {
+	Time       time.Time
+	Method     string
+	Route      string
+	Path       string
+	Query      string
+	URI        string
+	Protocol   string
+	Host       string
+	Status     int
+	Latency    time.Duration
+	BytesIn    int64
+	BytesOut   int64
+	RemoteIP   string
+	RemoteUser string
+	UserAgent  string
+	Referer    string
+	RequestID  string
+	Err        error
+	Timings    []Timing
+}
+
+// getResponseStatus returns the status which is (or will be) sent to the client.
+//
+// If the handler returned an error and didn't write the response, then the response
+// will be written by the echo's HTTP error handler after all middlewares are finished,
+// so the status is derived from the error.
+func getResponseStatus(res engine.Response, err error) int {
+	if err == nil || res.Committed() {
+		return res.Status()
+	}
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// getRequestProtocol returns the protocol version of the request, like "HTTP/1.1"
+func getRequestProtocol(req engine.Request) string {
+	switch r := req.(type) {
+	case *standard.Request:
+		return r.Request.Proto
+	case *fasthttp.Request:
+		if r.RequestCtx.Request.Header.IsHTTP11() {
+			return `HTTP/1.1`
+		}
+		return `HTTP/1.0`
+	}
+	return ``
+}
+
+// getRemoteUser returns the user name from the basic authentication header of the request
+func getRemoteUser(req engine.Request) string {
+	const prefix = `Basic `
+	auth := req.Header().Get(`Authorization`)
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ``
+	}
+	credentials, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return ``
+	}
+	if idx := bytes.IndexByte(credentials, ':'); idx != -1 {
+		return string(credentials[:idx])
+	}
+	return ``
+}
+
+// getResponseSize returns the amount of bytes of the response body
+func getResponseSize(res engine.Response) int64 {
+	if fastRes, ok := res.(*fasthttp.Response); ok {
+		// The body could be set directly through fasthttp, bypassing the counter of echo
+		return int64(len(fastRes.RequestCtx.Response.Body()))
+	}
+	return res.Size()
+}
+
+func newAccessLogEntry(c *LoggerContext, err error) *accessLogEntry {
+	req := c.Request()
+	res := c.Response()
+	return &accessLogEntry{
+		Time:       c.StartTime,
+		Method:     req.Method(),
+		Route:      c.Path(),
+		Path:       req.URL().Path(),
+		Query:      req.URL().QueryString(),
+		URI:        req.URI(),
+		Protocol:   getRequestProtocol(req),
+		Host:       req.Host(),
+		Status:     getResponseStatus(res, err),
+		Latency:    time.Since(c.StartTime),
+		BytesIn:    req.ContentLength(),
+		BytesOut:   getResponseSize(res),
+		RemoteIP:   req.RealIP(),
+		RemoteUser: getRemoteUser(req),
+		UserAgent:  req.UserAgent(),
+		Referer:    req.Referer(),
+		RequestID:  c.GetRequestID(),
+		Err:        err,
+		Timings:    c.Timings(),
+	}
+}
+
+func (entry *accessLogEntry) fields() Fields {
+	fields := Fields{
+		`what`:       `http_access`,
+		`method`:     entry.Method,
+		`route`:      entry.Route,
+		`url`:        entry.Path,
+		`http_code`:  entry.Status,
+		`latency`:    entry.Latency,
+		`bytes_in`:   entry.BytesIn,
+		`bytes_out`:  entry.BytesOut,
+		`client_ip`:  entry.RemoteIP,
+		`user_agent`: entry.UserAgent,
+	}
+	if entry.Err != nil {
+		fields[`error`] = entry.Err.Error()
+	}
+	if len(entry.Timings) > 0 {
+		fields[`timings`] = entry.Timings
+	}
+	return fields
+}
+
+// level returns the log level depending on the status class: 5xx are errors, 4xx are warnings
+func (entry *accessLogEntry) level() SinkLevel {
+	switch {
+	case entry.Status >= 500:
+		return SinkLevelError
+	case entry.Status >= 400:
+		return SinkLevelWarn
+	}
+	return SinkLevelInfo
+}
+
+// variable returns a value of a variable of the access log template
+func (entry *accessLogEntry) variable(name string) (string, bool) {
+	var value string
+	switch name {
+	case `remote_ip`:
+		value = entry.RemoteIP
+	case `remote_user`:
+		value = entry.RemoteUser
+	case `method`:
+		value = entry.Method
+	case `uri`:
+		value = entry.URI
+	case `path`:
+		value = entry.Path
+	case `route`:
+		value = entry.Route
+	case `query`:
+		value = entry.Query
+	case `protocol`:
+		value = entry.Protocol
+	case `host`:
+		value = entry.Host
+	case `status`:
+		value = strconv.Itoa(entry.Status)
+	case `latency`:
+		value = entry.Latency.String()
+	case `latency_ms`:
+		value = strconv.FormatFloat(float64(entry.Latency)/float64(time.Millisecond), 'f', 3, 64)
+	case `latency_us`:
+		value = strconv.FormatInt(int64(entry.Latency/time.Microsecond), 10)
+	case `bytes_in`:
+		value = strconv.FormatInt(entry.BytesIn, 10)
+	case `bytes_out`:
+		value = strconv.FormatInt(entry.BytesOut, 10)
+	case `bytes_out_clf`:
+		// CLF uses "-" instead of zero
+		if entry.BytesOut > 0 {
+			value = strconv.FormatInt(entry.BytesOut, 10)
+		}
+	case `request_id`:
+		value = entry.RequestID
+	case `user_agent`:
+		value = entry.UserAgent
+	case `referer`:
+		value = entry.Referer
+	case `time_rfc3339`:
+		value = entry.Time.Format(time.RFC3339)
+	case `time_common`:
+		value = entry.Time.Format(accessLogTimeCommonLayout)
+	case `error`:
+		if entry.Err != nil {
+			value = entry.Err.Error()
+		}
+	case `server_timing`:
+		value = formatServerTiming(entry.Timings)
+	default:
+		return ``, false
+	}
+	if value == `` {
+		return `-`, true
+	}
+	return escapeAccessLogValue(value), true
+}
+
+// escapeAccessLogValue escapes quotes, backslashes and non-printable characters
+// the same way Apache does, so a value could not break the format of the line
+func escapeAccessLogValue(s string) string {
+	needsEscaping := false
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c == '"' || c == '\\' || c < 0x20 || c == 0x7f {
+			needsEscaping = true
+			break
+		}
+	}
+	if !needsEscaping {
+		return s
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&buf, `\x%02x`, c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// write writes the access log entry of the request. Structured entries are written
+// regardless of the log level of the request (but they're still filtered by the
+// level of the logger, see NewLogrusSink). The access log is not affected by Options.Disable.
+func (l *accessLogger) write(c *LoggerContext, entry *accessLogEntry) {
+	if l == nil {
+		return
+	}
+
+	if l.template == nil {
+		message := fmt.Sprintf(`%v %v %v`, entry.Method, entry.Path, entry.Status)
+		c.sink.WithFields(entry.fields()).Write(entry.level(), message)
+		return
+	}
+
+	l.writerLock.Lock()
+	defer l.writerLock.Unlock()
+	l.buffer.Reset()
+	l.template.ExecuteFunc(&l.buffer, func(w io.Writer, tag string) (int, error) {
+		value, _ := entry.variable(tag)
+		return io.WriteString(w, value)
+	})
+	l.buffer.WriteByte('\n')
+	l.writer.Write(l.buffer.Bytes())
+}
@@ -0,0 +1,219 @@
+package echolog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// AuditEvent is a line of the audit log (see LoggerContext.Audit)
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Actor     string    `json:"actor,omitempty"`
+	Action    string    `json:"action"`
+	Fields    Fields    `json:"fields,omitempty"`
+	PrevHash  string    `json:"prev_hash,omitempty"` // The hash of the previous event (if hash chaining is enabled)
+	Hash      string    `json:"hash,omitempty"`      // SHA-256 of the event (without this field) if hash chaining is enabled
+}
+
+// hashAuditEvent returns the hash of the event (the field Hash is ignored).
+// The event is hashed in the form it's read back from the log (numbers are
+// kept as is, structs become objects with sorted keys, etc).
+func hashAuditEvent(event AuditEvent) (string, error) {
+	event.Hash = ``
+	b, err := json.Marshal(event)
+	if err != nil {
+		return ``, err
+	}
+	event, err = decodeAuditEvent(b)
+	if err != nil {
+		return ``, err
+	}
+	b, err = json.Marshal(event)
+	if err != nil {
+		return ``, err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func decodeAuditEvent(b []byte) (AuditEvent, error) {
+	var event AuditEvent
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err := decoder.Decode(&event)
+	return event, err
+}
+
+// auditLog is the state of an audit log shared by all audit writers
+// writing to the same io.Writer (for example by several Middleware-s),
+// so events are written one by one and the log is a single hash chain
+type auditLog struct {
+	sync.Mutex
+	prevHash string
+}
+
+// The registry of audit logs by writers, they're never released (like generators)
+var auditLogs = struct {
+	sync.Mutex
+	m map[io.Writer]*auditLog
+}{
+	m: map[io.Writer]*auditLog{},
+}
+
+// getAuditLog returns the state of the audit log written to the writer,
+// prevHash is used only if nothing is written to the writer yet
+func getAuditLog(writer io.Writer, prevHash string) *auditLog {
+	if !reflect.TypeOf(writer).Comparable() {
+		// Could not be a key of the map
+		return &auditLog{prevHash: prevHash}
+	}
+
+	auditLogs.Lock()
+	defer auditLogs.Unlock()
+	log := auditLogs.m[writer]
+	if log == nil {
+		log = &auditLog{prevHash: prevHash}
+		auditLogs.m[writer] = log
+	}
+	return log
+}
+
+type auditWriter struct {
+	writer    io.Writer
+	log       *auditLog
+	actorFunc func(c *LoggerContext) string
+	hashChain bool
+}
+
+func newAuditWriter(opts Options) *auditWriter {
+	if opts.AuditWriter == nil {
+		return nil
+	}
+	w := &auditWriter{
+		writer:    opts.AuditWriter,
+		log:       getAuditLog(opts.AuditWriter, opts.AuditPrevHash),
+		actorFunc: opts.AuditActorFunc,
+		hashChain: opts.AuditHashChain,
+	}
+	if w.actorFunc == nil {
+		w.actorFunc = func(c *LoggerContext) string {
+			return getRemoteUser(c.Request())
+		}
+	}
+	return w
+}
+
+// write writes the event as a JSON line, events are chained by hashes if it's enabled
+func (w *auditWriter) write(event AuditEvent) error {
+	w.log.Lock()
+	defer w.log.Unlock()
+
+	if w.hashChain {
+		event.PrevHash = w.log.prevHash
+		hash, err := hashAuditEvent(event)
+		if err != nil {
+			return err
+		}
+		event.Hash = hash
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := w.writer.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	if w.hashChain {
+		w.log.prevHash = event.Hash
+	}
+	return nil
+}
+
+// Audit writes an audit event (for example action "campaign.update" with the
+// ID of the campaign in fields) to Options.AuditWriter. Audit events are
+// written regardless of the log level of the request. Each event has the
+// request ID, the actor (see Options.AuditActorFunc) and the time.
+//
+// If Options.AuditWriter is not set then the event is written through the
+// logger of the request (regardless of the log level as well).
+func (ctx *LoggerContext) Audit(action string, fields Fields) error {
+	event := AuditEvent{
+		Time:      time.Now(),
+		RequestID: ctx.requestID,
+		Action:    action,
+		Fields:    fields,
+	}
+
+	var w *auditWriter
+	if ctx.generator != nil {
+		w = ctx.generator.auditWriter
+	}
+	if w == nil {
+		ctx.sink.WithFields(Fields{
+			`what`:         `audit`,
+			`audit_fields`: fields,
+		}).Write(SinkLevelInfo, action)
+		return nil
+	}
+
+	event.Actor = w.actorFunc(ctx)
+	if err := w.write(event); err != nil {
+		ctx.sink.WithFields(Fields{
+			`what`:         `audit`,
+			`audit_action`: action,
+			`audit_fields`: fields,
+		}).Write(SinkLevelError, fmt.Sprintf(`Unable to write an audit event: %v`, err))
+		return err
+	}
+	return nil
+}
+
+// VerifyAuditLog checks the hash chain of an audit log written with
+// Options.AuditHashChain. It returns an error on the first modified,
+// removed or inserted event.
+//
+// The chain of a log starts with an empty hash. The process should continue
+// the chain on a restart (see AuditLogLastHash), otherwise the log is reported
+// as broken at the first event written after the restart.
+func VerifyAuditLog(r io.Reader) error {
+	_, err := AuditLogLastHash(r)
+	return err
+}
+
+// AuditLogLastHash verifies the audit log (see VerifyAuditLog) and returns
+// the hash of its last event. It should be passed as Options.AuditPrevHash
+// to continue appending to the log after a restart.
+func AuditLogLastHash(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	var prevHash string
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		event, err := decodeAuditEvent(scanner.Bytes())
+		if err != nil {
+			return ``, fmt.Errorf(`line %d: %w`, lineNumber, err)
+		}
+		if event.PrevHash != prevHash {
+			return ``, fmt.Errorf(`line %d: the hash chain is broken`, lineNumber)
+		}
+		hash, err := hashAuditEvent(event)
+		if err != nil {
+			return ``, fmt.Errorf(`line %d: %w`, lineNumber, err)
+		}
+		if hash != event.Hash {
+			return ``, fmt.Errorf(`line %d: the event is modified`, lineNumber)
+		}
+		prevHash = event.Hash
+	}
+	return prevHash, scanner.Err()
+}
@@ -0,0 +1,101 @@
+package echolog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestAuditEvents(t *testing.T, w *auditWriter, fromID, toID int) {
+	for id := fromID; id <= toID; id++ {
+		err := w.write(AuditEvent{
+			Time:      time.Date(2020, 1, 1, 0, 0, id, 0, time.UTC),
+			RequestID: `request`,
+			Action:    `campaign.update`,
+			Fields:    Fields{`id`: id},
+		})
+		if err != nil {
+			t.Fatalf(`unable to write an audit event: %v`, err)
+		}
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestAuditEvents(t, newAuditWriter(Options{AuditWriter: &buf, AuditHashChain: true}), 1, 3)
+	lines := strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	for _, tc := range []struct {
+		name     string
+		log      string
+		expected string
+	}{
+		{
+			name: `valid`,
+			log:  lines[0] + lines[1] + lines[2],
+		},
+		{
+			name:     `tampered event`,
+			log:      lines[0] + strings.Replace(lines[1], `"id":2`, `"id":4`, 1) + lines[2],
+			expected: `line 2: the event is modified`,
+		},
+		{
+			name:     `removed line`,
+			log:      lines[0] + lines[2],
+			expected: `line 2: the hash chain is broken`,
+		},
+		{
+			name:     `inserted line`,
+			log:      lines[0] + lines[0] + lines[1] + lines[2],
+			expected: `line 2: the hash chain is broken`,
+		},
+		{
+			name:     `removed first line`,
+			log:      lines[1] + lines[2],
+			expected: `line 1: the hash chain is broken`,
+		},
+	} {
+		err := VerifyAuditLog(strings.NewReader(tc.log))
+		var errString string
+		if err != nil {
+			errString = err.Error()
+		}
+		if errString != tc.expected {
+			t.Errorf(`%v: VerifyAuditLog() = %q; expected %q`, tc.name, errString, tc.expected)
+		}
+	}
+}
+
+func TestAuditLogSharedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w0 := newAuditWriter(Options{AuditWriter: &buf, AuditHashChain: true})
+	w1 := newAuditWriter(Options{AuditWriter: &buf, AuditHashChain: true})
+	writeTestAuditEvents(t, w0, 1, 2)
+	writeTestAuditEvents(t, w1, 3, 4)
+	writeTestAuditEvents(t, w0, 5, 5)
+
+	if err := VerifyAuditLog(&buf); err != nil {
+		t.Errorf(`VerifyAuditLog() = %v; expected nil`, err)
+	}
+}
+
+func TestAuditLogRestart(t *testing.T) {
+	var before, after, afterUnseeded bytes.Buffer
+	writeTestAuditEvents(t, newAuditWriter(Options{AuditWriter: &before, AuditHashChain: true}), 1, 2)
+
+	lastHash, err := AuditLogLastHash(bytes.NewReader(before.Bytes()))
+	if err != nil {
+		t.Fatalf(`AuditLogLastHash() = %v`, err)
+	}
+	writeTestAuditEvents(t, newAuditWriter(Options{AuditWriter: &after, AuditHashChain: true, AuditPrevHash: lastHash}), 3, 4)
+	writeTestAuditEvents(t, newAuditWriter(Options{AuditWriter: &afterUnseeded, AuditHashChain: true}), 3, 4)
+
+	if err := VerifyAuditLog(strings.NewReader(before.String() + after.String())); err != nil {
+		t.Errorf(`VerifyAuditLog() of the continued log = %v; expected nil`, err)
+	}
+	err = VerifyAuditLog(strings.NewReader(before.String() + afterUnseeded.String()))
+	if err == nil || err.Error() != `line 3: the hash chain is broken` {
+		t.Errorf(`VerifyAuditLog() of the restarted log = %v; expected the broken chain at line 3`, err)
+	}
+}
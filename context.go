@@ -0,0 +1,42 @@
+package echolog
+
+import (
+	"context"
+
+	"github.com/trafficstars/echo/engine/standard"
+)
+
+type loggerContextKey struct{}
+
+// NewContext returns a copy of the context.Context which carries the logger,
+// it could be retrieved with FromContext
+func NewContext(ctx context.Context, ctxLogger *LoggerContextLogger) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, loggerContextKey{}, ctxLogger)
+}
+
+// FromContext returns the logger stored in the context.Context by NewContext
+// (or by Middleware). If there's no logger then GetDefaultContextLogger() is returned.
+//
+// The logger stored by Middleware is valid only until the end of the request.
+func FromContext(ctx context.Context) *LoggerContextLogger {
+	if ctx != nil {
+		if ctxLogger, ok := ctx.Value(loggerContextKey{}).(*LoggerContextLogger); ok && ctxLogger != nil {
+			return ctxLogger
+		}
+	}
+	return GetDefaultContextLogger()
+}
+
+// storeInStdContext stores the logger into the context.Context of echo
+// (see echo.Context.StdContext) and of the request if the engine supports it
+// (engine/standard), so it's available to FromContext
+func (ctx *LoggerContext) storeInStdContext() {
+	ctx.SetStdContext(NewContext(ctx.StdContext(), &ctx.contextLogger))
+
+	if stdReq, ok := ctx.Request().(*standard.Request); ok {
+		stdReq.Request = stdReq.Request.WithContext(NewContext(stdReq.Request.Context(), &ctx.contextLogger))
+	}
+}
@@ -0,0 +1,98 @@
+package echolog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/trafficstars/echo/engine/fasthttp"
+)
+
+// curlSkipHeaders are headers which are set by curl itself
+var curlSkipHeaders = map[string]bool{
+	`content-length`:    true,
+	`host`:              true,
+	`connection`:        true,
+	`transfer-encoding`: true,
+}
+
+// shellQuote quotes a string to be safely passed as an argument in a POSIX shell
+func shellQuote(s string) string {
+	return `'` + strings.Replace(s, `'`, `'\''`, -1) + `'`
+}
+
+// buildCurlCommand returns a curl command line
+func buildCurlCommand(method, url string, headers map[string]string, body string) string {
+	var buf strings.Builder
+	buf.WriteString(`curl -X `)
+	buf.WriteString(shellQuote(method))
+
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		if curlSkipHeaders[strings.ToLower(name)] {
+			continue
+		}
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		buf.WriteString(` -H `)
+		buf.WriteString(shellQuote(name + `: ` + headers[name]))
+	}
+
+	if body != `` {
+		buf.WriteString(` --data-binary `)
+		buf.WriteString(shellQuote(body))
+	}
+
+	buf.WriteString(` `)
+	buf.WriteString(shellQuote(url))
+	return buf.String()
+}
+
+// AsCurl returns a curl command which reproduces the request. Sensitive
+// headers and body fields are redacted the same way as in exchange logs.
+//
+// The body is included only if it was captured by the middleware (or
+// if it's kept in memory by the engine, like in fasthttp) and if it's a text.
+// A truncated (or not yet fully read) body is omitted, because the command
+// would send a different request; a shell comment says so.
+func (ctx *LoggerContext) AsCurl() string {
+	var (
+		headerRedactor *headerRedactor
+		bodyRedactor   *bodyRedactor
+	)
+	if ctx.generator != nil {
+		headerRedactor = ctx.generator.headerRedactor
+		bodyRedactor = ctx.generator.bodyFormatter.redactor
+	}
+
+	req := ctx.Request()
+
+	var body capturedBody
+	if ctx.requestBodyRecorder != nil {
+		body = ctx.requestBodyRecorder.peek()
+	} else if fastReq, ok := req.(*fasthttp.Request); ok {
+		body = captureBodyBytes(fastReq.Request.Body(), -1, false)
+	}
+
+	var bodyString string
+	if len(body.data) > 0 && isText(body.data) && !body.truncated {
+		bodyString, _ = bodyRedactor.redact(string(body.data), req.Header().Get(`Content-Type`))
+	}
+
+	scheme := req.Scheme()
+	if scheme == `` {
+		scheme = `http`
+	}
+
+	command := buildCurlCommand(
+		req.Method(),
+		scheme+`://`+req.Host()+req.URI(),
+		getHeaders(req.Header(), headerRedactor),
+		bodyString,
+	)
+	if body.truncated {
+		command += ` # the body is omitted: it's truncated`
+	}
+	return command
+}
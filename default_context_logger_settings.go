@@ -10,8 +10,16 @@ type defaultContextLoggerSettingsT struct {
 	defaultLogLevel          labstacklog.Lvl
 	debugLogLevelFraction    float32
 	enableStackTraceFraction float32
+	fatalPolicy              FatalPolicy
+	fatalExitHook            func()
 }
 
 var defaultContextLoggerSettings = defaultContextLoggerSettingsT{
 	defaultLogLevel: DefaultContextLoggerLevel,
 }
+
+// SetDefaultFatalPolicy sets the FatalPolicy of loggers returned by GetDefaultContextLogger()
+func SetDefaultFatalPolicy(newFatalPolicy FatalPolicy, newFatalExitHook func()) {
+	defaultContextLoggerSettings.fatalPolicy = newFatalPolicy
+	defaultContextLoggerSettings.fatalExitHook = newFatalExitHook
+}
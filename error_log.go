@@ -0,0 +1,94 @@
+package echolog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	labstacklog "github.com/labstack/gommon/log"
+	"github.com/trafficstars/echo"
+)
+
+// ErrorLogOptions configures logging of errors returned by handlers
+type ErrorLogOptions struct {
+	Disable               bool                 // Do not log errors returned by handlers
+	ClientErrorLevel      labstacklog.Lvl      // The level for *echo.HTTPError with 4xx codes (WARN by default, OFF to do not log them)
+	ServerErrorLevel      labstacklog.Lvl      // The level for *echo.HTTPError with 5xx codes and for other errors (ERROR by default, OFF to do not log them)
+	IsClientErrorLevelSet bool                 // Use ClientErrorLevel even if it's zero (TRACE)
+	IsServerErrorLevelSet bool                 // Use ServerErrorLevel even if it's zero (TRACE)
+	IgnoreErrors          []error              // Errors (checked with errors.Is) which shouldn't be logged, for example echo.ErrNotFound
+	IgnoreFunc            func(err error) bool // If returns true then the error is not logged
+}
+
+type errorLogger struct {
+	clientErrorLevel labstacklog.Lvl
+	serverErrorLevel labstacklog.Lvl
+	ignoreErrors     []error
+	ignoreFunc       func(err error) bool
+}
+
+func newErrorLogger(opts ErrorLogOptions) *errorLogger {
+	if opts.Disable {
+		return nil
+	}
+	l := &errorLogger{
+		clientErrorLevel: opts.ClientErrorLevel,
+		serverErrorLevel: opts.ServerErrorLevel,
+		ignoreErrors:     opts.IgnoreErrors,
+		ignoreFunc:       opts.IgnoreFunc,
+	}
+	if l.clientErrorLevel == labstacklog.Lvl(0) && !opts.IsClientErrorLevelSet {
+		l.clientErrorLevel = labstacklog.WARN
+	}
+	if l.serverErrorLevel == labstacklog.Lvl(0) && !opts.IsServerErrorLevelSet {
+		l.serverErrorLevel = labstacklog.ERROR
+	}
+	return l
+}
+
+func (l *errorLogger) isIgnored(err error) bool {
+	for _, ignoredErr := range l.ignoreErrors {
+		if err == ignoredErr || errors.Is(err, ignoredErr) {
+			return true
+		}
+	}
+	return l.ignoreFunc != nil && l.ignoreFunc(err)
+}
+
+// write logs an error returned by the handler. Errors are written regardless
+// of the log level of the request (like the access log).
+func (l *errorLogger) write(c *LoggerContext, err error) {
+	if l == nil || err == nil || l.isIgnored(err) {
+		return
+	}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		// Already logged by the panic recovery
+		return
+	}
+
+	status := http.StatusInternalServerError
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.Code
+	}
+
+	level := l.serverErrorLevel
+	if status < 500 {
+		level = l.clientErrorLevel
+	}
+	if level >= labstacklog.OFF {
+		return
+	}
+
+	c.sink.WithFields(Fields{
+		`what`:       `http_error`,
+		`method`:     c.Request().Method(),
+		`route`:      c.Path(),
+		`url`:        c.Request().URL().Path(),
+		`http_code`:  status,
+		`latency`:    time.Since(c.StartTime),
+		`error_type`: fmt.Sprintf(`%T`, err),
+	}).Write(getSinkLevel(level), err.Error())
+}
@@ -0,0 +1,113 @@
+package echolog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// DefaultMaxBodySize is the maximal size of a request / response body
+// to be logged if the limit is not set in Options
+const DefaultMaxBodySize = 64 * 1024
+
+// capturedBody is a (possibly truncated) copy of a request / response body
+type capturedBody struct {
+	data      []byte
+	length    int64  // the length of the full body
+	truncated bool   // "data" contains only the first "limit" bytes of the body
+	sha256    string // a hash of the full body, set only if it's truncated and hashing is enabled
+}
+
+// bodyCapturer is an io.Writer which remembers only the first "limit" bytes
+// written to it, but counts (and optionally hashes) all of them
+type bodyCapturer struct {
+	data   []byte
+	limit  int // negative means no limit
+	length int64
+	hash   hash.Hash
+}
+
+func newBodyCapturer(limit int, withHash bool) *bodyCapturer {
+	c := &bodyCapturer{
+		limit: limit,
+	}
+	if withHash {
+		c.hash = sha256.New()
+	}
+	return c
+}
+
+func (c *bodyCapturer) Write(b []byte) (int, error) {
+	c.length += int64(len(b))
+	if c.hash != nil {
+		c.hash.Write(b)
+	}
+
+	chunk := b
+	if c.limit >= 0 {
+		left := c.limit - len(c.data)
+		if left < 0 {
+			left = 0
+		}
+		if len(chunk) > left {
+			chunk = chunk[:left]
+		}
+	}
+	c.data = append(c.data, chunk...)
+
+	return len(b), nil
+}
+
+func (c *bodyCapturer) result() capturedBody {
+	body := capturedBody{
+		data:      c.data,
+		length:    c.length,
+		truncated: int64(len(c.data)) < c.length,
+	}
+	if body.truncated && c.hash != nil {
+		body.sha256 = hex.EncodeToString(c.hash.Sum(nil))
+	}
+	return body
+}
+
+// captureBody reads the whole reader, but remembers only the first "limit" bytes
+func captureBody(r io.Reader, limit int, withHash bool) capturedBody {
+	c := newBodyCapturer(limit, withHash)
+	if r != nil {
+		io.Copy(c, r)
+	}
+	return c.result()
+}
+
+// captureBodyBytes is the same as captureBody, but for a body which is already in memory
+func captureBodyBytes(b []byte, limit int, withHash bool) capturedBody {
+	c := newBodyCapturer(limit, withHash)
+	c.Write(b)
+	return c.result()
+}
+
+// fields returns truncation markers to be added to the log entry
+func (body capturedBody) fields() Fields {
+	if !body.truncated {
+		return nil
+	}
+	fields := Fields{
+		`body_truncated`: true,
+		`body_length`:    body.length,
+	}
+	if body.sha256 != `` {
+		fields[`body_sha256`] = body.sha256
+	}
+	return fields
+}
+
+func normalizeMaxBodySize(size int) int {
+	switch {
+	case size == 0:
+		return DefaultMaxBodySize
+	case size < 0:
+		return -1
+	}
+	return size
+}
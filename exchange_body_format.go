@@ -0,0 +1,271 @@
+package echolog
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+)
+
+// BinaryBodyMode defines how to log bodies of binary content types (images, protobuf, etc)
+type BinaryBodyMode int
+
+const (
+	// BinaryBodySkip replaces a binary body with a short description (content type and size)
+	BinaryBodySkip BinaryBodyMode = iota
+
+	// BinaryBodyBase64 logs a binary body encoded with base64
+	BinaryBodyBase64
+)
+
+// JSONBodyFormat defines how to log JSON bodies
+type JSONBodyFormat int
+
+const (
+	// JSONBodyAsIs logs JSON bodies without reformatting
+	JSONBodyAsIs JSONBodyFormat = iota
+
+	// JSONBodyCompact removes insignificant whitespace from JSON bodies
+	JSONBodyCompact
+
+	// JSONBodyPretty indents JSON bodies
+	JSONBodyPretty
+)
+
+// binaryContentTypes is the list of content types (or their prefixes if ends with "/")
+// which are considered binary regardless of the content
+var binaryContentTypes = []string{
+	`image/`,
+	`audio/`,
+	`video/`,
+	`font/`,
+	`application/octet-stream`,
+	`application/protobuf`,
+	`application/x-protobuf`,
+	`application/vnd.google.protobuf`,
+	`application/grpc`,
+	`application/msgpack`,
+	`application/x-msgpack`,
+	`application/pdf`,
+	`application/zip`,
+	`application/gzip`,
+	`application/x-gzip`,
+}
+
+// multipartSummary is logged instead of a body of a multipart form
+type multipartSummary struct {
+	Fields     []string               `json:"fields,omitempty"`
+	Files      []multipartFileSummary `json:"files,omitempty"`
+	Incomplete bool                   `json:"incomplete,omitempty"`
+}
+
+type multipartFileSummary struct {
+	Field    string `json:"field"`
+	FileName string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// bodyFormatter converts a captured body to a string to be logged
+// according to the Content-Type and Content-Encoding of the body
+type bodyFormatter struct {
+	redactor       *bodyRedactor
+	binaryBodyMode BinaryBodyMode
+	jsonBodyFormat JSONBodyFormat
+}
+
+func newBodyFormatter(opts Options, redactor *bodyRedactor) *bodyFormatter {
+	return &bodyFormatter{
+		redactor:       redactor,
+		binaryBodyMode: opts.BinaryBodyMode,
+		jsonBodyFormat: opts.JSONBodyFormat,
+	}
+}
+
+// decodeBody decompresses a body according to the Content-Encoding. The decompressed body
+// is limited by maxSize bytes to be protected from decompression bombs.
+func decodeBody(data []byte, contentEncoding string, maxSize int) (decoded []byte, isTruncated bool, err error) {
+	var reader io.Reader
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case ``, `identity`:
+		return data, false, nil
+	case `gzip`, `x-gzip`:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false, err
+		}
+		reader = gzipReader
+	case `deflate`:
+		// "deflate" is supposed to be zlib-wrapped, but some clients send a raw deflate stream
+		zlibReader, err := zlib.NewReader(bytes.NewReader(data))
+		if err == nil {
+			reader = zlibReader
+		} else {
+			reader = flate.NewReader(bytes.NewReader(data))
+		}
+	case `br`:
+		reader = brotli.NewReader(bytes.NewReader(data))
+	default:
+		return nil, false, fmt.Errorf(`unsupported content encoding "%v"`, contentEncoding)
+	}
+
+	if maxSize >= 0 {
+		reader = io.LimitReader(reader, int64(maxSize)+1)
+	}
+
+	// The body could be truncated, so we use everything we were able to decode
+	decoded, err = ioutil.ReadAll(reader)
+	if len(decoded) > 0 {
+		err = nil
+	}
+	if maxSize >= 0 && len(decoded) > maxSize {
+		decoded = decoded[:maxSize]
+		isTruncated = true
+	}
+	return decoded, isTruncated, err
+}
+
+func isBinaryContentType(mediaType string) bool {
+	for _, binaryContentType := range binaryContentTypes {
+		if strings.HasSuffix(binaryContentType, `/`) {
+			if strings.HasPrefix(mediaType, binaryContentType) {
+				return true
+			}
+			continue
+		}
+		if mediaType == binaryContentType || strings.HasPrefix(mediaType, binaryContentType+`+`) {
+			return true
+		}
+	}
+	return false
+}
+
+// isText checks if the data is a valid UTF-8 text without NUL bytes. The last
+// rune is allowed to be incomplete, because the data could be truncated.
+func isText(data []byte) bool {
+	if bytes.IndexByte(data, 0) != -1 {
+		return false
+	}
+	if utf8.Valid(data) {
+		return true
+	}
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			return !utf8.FullRune(data[i:]) && utf8.Valid(data[:i])
+		}
+	}
+	return false
+}
+
+func summarizeMultipart(data []byte, boundary string) multipartSummary {
+	var summary multipartSummary
+	reader := multipart.NewReader(bytes.NewReader(data), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			summary.Incomplete = true
+			break
+		}
+
+		size, err := io.Copy(ioutil.Discard, part)
+		if part.FileName() == `` {
+			summary.Fields = append(summary.Fields, part.FormName())
+		} else {
+			summary.Files = append(summary.Files, multipartFileSummary{
+				Field:    part.FormName(),
+				FileName: part.FileName(),
+				Size:     size,
+			})
+		}
+		if err != nil {
+			summary.Incomplete = true
+			break
+		}
+	}
+	return summary
+}
+
+func (f *bodyFormatter) formatJSON(s string) string {
+	var buf bytes.Buffer
+	switch f.jsonBodyFormat {
+	case JSONBodyCompact:
+		if json.Compact(&buf, []byte(s)) == nil {
+			return buf.String()
+		}
+	case JSONBodyPretty:
+		if json.Indent(&buf, []byte(s), ``, `  `) == nil {
+			return buf.String()
+		}
+	}
+	return s
+}
+
+// format returns the body to be logged and additional fields describing it
+func (f *bodyFormatter) format(body capturedBody, contentType, contentEncoding string, maxSize int) (string, Fields) {
+	fields := body.fields()
+	if len(body.data) == 0 {
+		return ``, fields
+	}
+	setField := func(key string, value interface{}) {
+		if fields == nil {
+			fields = Fields{}
+		}
+		fields[key] = value
+	}
+
+	data := body.data
+	if contentEncoding != `` {
+		decoded, isTruncated, err := decodeBody(data, contentEncoding, maxSize)
+		if err != nil {
+			setField(`body_decode_error`, err.Error())
+		} else {
+			data = decoded
+			setField(`body_content_encoding`, contentEncoding)
+			if isTruncated {
+				setField(`body_decoded_truncated`, true)
+			}
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, `multipart/`) && params[`boundary`] != ``:
+		summary := summarizeMultipart(data, params[`boundary`])
+		setField(`body_multipart`, summary)
+		return fmt.Sprintf(`[multipart: %d fields, %d files]`, len(summary.Fields), len(summary.Files)), fields
+
+	case isBinaryContentType(mediaType) || !isText(data):
+		if f.binaryBodyMode == BinaryBodyBase64 {
+			setField(`body_base64`, true)
+			return base64.StdEncoding.EncodeToString(data), fields
+		}
+		return fmt.Sprintf(`[binary: %v, %d bytes]`, mediaType, body.length), fields
+	}
+
+	s, ok := f.redactor.redact(string(data), contentType)
+	if !ok {
+		setField(`body_redaction_failed`, true)
+		return `[redaction failed]`, fields
+	}
+	if isJSONContentType(contentType) || looksLikeJSON(s) {
+		s = f.formatJSON(s)
+	}
+	return s, fields
+}
@@ -0,0 +1,464 @@
+package echolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PIIDetector finds sensitive data in bodies of requests / responses using a regular expression
+type PIIDetector struct {
+	Name     string                  // Used in the replacement: "[REDACTED:<Name>]"
+	Pattern  *regexp.Regexp          // What to search for
+	Validate func(match string) bool // Optional: an additional check of a match (to reduce false positives)
+}
+
+// DefaultRedactedBodyFields is the list of JSON field selectors which are redacted
+// unless BodyRedactionOptions.DisableDefaults is set.
+var DefaultRedactedBodyFields = []string{
+	`$..password`,
+	`$..passwd`,
+	`$..secret`,
+	`$..access_token`,
+	`$..refresh_token`,
+	`$..card_number`,
+	`$..cvv`,
+}
+
+// DefaultPIIDetectors is the list of detectors which are applied
+// unless BodyRedactionOptions.DisableDefaults is set.
+var DefaultPIIDetectors = []PIIDetector{
+	{
+		Name:    `email`,
+		Pattern: regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+	},
+	{
+		Name:     `card_number`,
+		Pattern:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		Validate: isLuhnValid,
+	},
+}
+
+// BodyRedactionOptions configures redaction of request / response bodies in exchange logs
+//
+// Fields are selectors in a JSONPath-like syntax:
+// * "$.user.password" -- a field "password" of the object "user";
+// * "$..card_number" -- a field "card_number" on any depth;
+// * "$.items[*].token", "$.items[0].token" -- a field of all (or a specific) elements of an array;
+// * "$.tokens.*" -- all fields of an object.
+//
+// Fields are applied to JSON bodies only, while Detectors are applied to
+// string values of JSON bodies and to other bodies as is.
+type BodyRedactionOptions struct {
+	Fields          []string      // Selectors of JSON fields to be redacted in addition to DefaultRedactedBodyFields
+	Detectors       []PIIDetector // Detectors to be applied in addition to DefaultPIIDetectors
+	DisableDefaults bool          // Do not use DefaultRedactedBodyFields and DefaultPIIDetectors
+	Mask            string        // The replacement for values of matched fields, "[REDACTED]" by default
+}
+
+type jsonPathStep struct {
+	key       string // `*` means any key (or any index)
+	index     int    // -1 if the step is not an array index
+	recursive bool   // the step matches on any depth (`..`)
+}
+
+type bodyRedactor struct {
+	selectors [][]jsonPathStep
+	detectors []PIIDetector
+	mask      string
+
+	// Keys to be masked in bodies which cannot be parsed as JSON (for
+	// example truncated ones), see maskJSONKeys
+	leafKeys map[string]bool
+	// There's a selector without a key (like "$[0]"), so a body which
+	// cannot be parsed as JSON cannot be redacted
+	hasKeylessSelector bool
+}
+
+func newBodyRedactor(opts BodyRedactionOptions, sink Sink) *bodyRedactor {
+	r := &bodyRedactor{
+		mask: opts.Mask,
+	}
+	if r.mask == `` {
+		r.mask = defaultRedactMask
+	}
+
+	var fields []string
+	if !opts.DisableDefaults {
+		fields = append(fields, DefaultRedactedBodyFields...)
+		r.detectors = append(r.detectors, DefaultPIIDetectors...)
+	}
+	fields = append(fields, opts.Fields...)
+
+	for _, detector := range opts.Detectors {
+		if detector.Pattern == nil {
+			writeSinkf(sink, SinkLevelError, `PII detector "%v" has no pattern`, detector.Name)
+			continue
+		}
+		r.detectors = append(r.detectors, detector)
+	}
+
+	for _, field := range fields {
+		steps, ok := parseJSONPath(field)
+		if !ok {
+			writeSinkf(sink, SinkLevelError, `Invalid body redaction selector "%v"`, field)
+			continue
+		}
+		r.selectors = append(r.selectors, steps)
+
+		if key, ok := getSelectorLeafKey(steps); ok {
+			if r.leafKeys == nil {
+				r.leafKeys = map[string]bool{}
+			}
+			r.leafKeys[key] = true
+		} else {
+			r.hasKeylessSelector = true
+		}
+	}
+
+	return r
+}
+
+// parseJSONPath parses selectors like "$.a..b[*].c[0]"
+func parseJSONPath(s string) ([]jsonPathStep, bool) {
+	if !strings.HasPrefix(s, `$`) {
+		return nil, false
+	}
+	s = s[1:]
+
+	var steps []jsonPathStep
+	for len(s) > 0 {
+		step := jsonPathStep{index: -1}
+		switch {
+		case strings.HasPrefix(s, `..`):
+			step.recursive = true
+			s = s[2:]
+		case s[0] == '.':
+			s = s[1:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return nil, false
+			}
+			inner := s[1:end]
+			s = s[end+1:]
+			if inner == `*` {
+				step.key = `*`
+			} else if idx, err := strconv.Atoi(inner); err == nil && idx >= 0 {
+				step.index = idx
+			} else if unquoted := strings.Trim(inner, `'"`); len(unquoted) == len(inner)-2 {
+				step.key = unquoted
+			} else {
+				return nil, false
+			}
+			steps = append(steps, step)
+			continue
+		default:
+			return nil, false
+		}
+
+		if len(s) > 0 && s[0] == '[' {
+			if !step.recursive {
+				return nil, false
+			}
+			// "..[*]" and similar: the bracket part is parsed on the next iteration
+			// and inherits the recursive flag
+			nextSteps, ok := parseJSONPath(`$` + s)
+			if !ok || len(nextSteps) == 0 {
+				return nil, false
+			}
+			nextSteps[0].recursive = true
+			return append(steps, nextSteps...), true
+		}
+
+		end := strings.IndexAny(s, `.[`)
+		if end == -1 {
+			end = len(s)
+		}
+		step.key = s[:end]
+		s = s[end:]
+		if step.key == `` {
+			return nil, false
+		}
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		return nil, false
+	}
+	return steps, true
+}
+
+// getSelectorLeafKey returns the last named key of the selector. Everything
+// under this key is masked if the selector cannot be applied as is.
+func getSelectorLeafKey(steps []jsonPathStep) (string, bool) {
+	for idx := len(steps) - 1; idx >= 0; idx-- {
+		if steps[idx].index == -1 && steps[idx].key != `*` {
+			return steps[idx].key, true
+		}
+	}
+	return ``, false
+}
+
+func (step jsonPathStep) matchesKey(key string) bool {
+	return step.index == -1 && (step.key == `*` || step.key == key)
+}
+
+func (step jsonPathStep) matchesIndex(idx int) bool {
+	return step.key == `*` || step.index == idx
+}
+
+// applySelector replaces all values matched by steps within the node,
+// isChanged is set if anything is replaced
+func (r *bodyRedactor) applySelector(node interface{}, steps []jsonPathStep, isChanged *bool) interface{} {
+	if len(steps) == 0 {
+		*isChanged = true
+		return r.mask
+	}
+	step := steps[0]
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if step.matchesKey(key) {
+				v[key] = r.applySelector(child, steps[1:], isChanged)
+			} else if step.recursive {
+				v[key] = r.applySelector(child, steps, isChanged)
+			}
+		}
+	case []interface{}:
+		for idx, child := range v {
+			if step.matchesIndex(idx) {
+				v[idx] = r.applySelector(child, steps[1:], isChanged)
+			} else if step.recursive {
+				v[idx] = r.applySelector(child, steps, isChanged)
+			}
+		}
+	}
+
+	return node
+}
+
+// applyDetectors redacts detected PII within all string values of the node,
+// isChanged is set if anything is redacted
+func (r *bodyRedactor) applyDetectors(node interface{}, isChanged *bool) interface{} {
+	switch v := node.(type) {
+	case string:
+		redacted := r.redactText(v)
+		if redacted != v {
+			*isChanged = true
+		}
+		return redacted
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = r.applyDetectors(child, isChanged)
+		}
+	case []interface{}:
+		for idx, child := range v {
+			v[idx] = r.applyDetectors(child, isChanged)
+		}
+	}
+	return node
+}
+
+func (r *bodyRedactor) redactText(s string) string {
+	for _, detector := range r.detectors {
+		detector := detector
+		s = detector.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if detector.Validate != nil && !detector.Validate(match) {
+				return match
+			}
+			return `[REDACTED:` + detector.Name + `]`
+		})
+	}
+	return s
+}
+
+func isJSONContentType(contentType string) bool {
+	if idx := strings.IndexByte(contentType, ';'); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	return contentType == `application/json` || strings.HasSuffix(contentType, `+json`)
+}
+
+func looksLikeJSON(body string) bool {
+	body = strings.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// redact masks sensitive data in a body of a request / response.
+//
+// JSON bodies are redacted by selectors and detectors, other bodies are
+// redacted by detectors only. If a JSON body cannot be parsed (for example
+// if it's truncated) then values of the last keys of selectors are masked
+// wherever they are. It returns false if the body cannot be redacted
+// (it should not be logged then).
+func (r *bodyRedactor) redact(body string, contentType string) (string, bool) {
+	if r == nil || len(body) == 0 {
+		return body, true
+	}
+
+	if isJSONContentType(contentType) || looksLikeJSON(body) {
+		if redacted, ok := r.redactJSON(body); ok {
+			return redacted, true
+		}
+		if r.hasKeylessSelector {
+			return ``, false
+		}
+		body = maskJSONKeys(body, r.leafKeys, r.mask)
+	}
+
+	return r.redactText(body), true
+}
+
+func (r *bodyRedactor) redactJSON(body string) (string, bool) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return ``, false
+	}
+
+	isChanged := false
+	for _, steps := range r.selectors {
+		doc = r.applySelector(doc, steps, &isChanged)
+	}
+	doc = r.applyDetectors(doc, &isChanged)
+	if !isChanged {
+		// Keep the body as is (the order of keys, spaces, etc)
+		return body, true
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return ``, false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// maskJSONKeys replaces values of the keys in a JSON text which is not
+// necessarily valid (for example truncated). An unterminated value is
+// masked up to the end of the text.
+func maskJSONKeys(body string, keys map[string]bool, mask string) string {
+	if len(keys) == 0 {
+		return body
+	}
+	quotedMask, _ := json.Marshal(mask)
+
+	var buf strings.Builder
+	pos := 0
+	for idx := 0; idx < len(body); {
+		if body[idx] != '"' {
+			idx++
+			continue
+		}
+		keyEnd := skipJSONString(body, idx)
+		colon := skipJSONSpaces(body, keyEnd)
+		if colon >= len(body) || body[colon] != ':' {
+			idx = keyEnd
+			continue
+		}
+		var key string
+		if json.Unmarshal([]byte(body[idx:keyEnd]), &key) != nil || !keys[key] {
+			idx = colon + 1
+			continue
+		}
+
+		valueStart := skipJSONSpaces(body, colon+1)
+		valueEnd := skipJSONValue(body, valueStart)
+		buf.WriteString(body[pos:valueStart])
+		buf.Write(quotedMask)
+		pos = valueEnd
+		idx = valueEnd
+	}
+	if pos == 0 {
+		return body
+	}
+	buf.WriteString(body[pos:])
+	return buf.String()
+}
+
+// skipJSONString returns the position after the string which starts at idx (with a quote)
+func skipJSONString(s string, idx int) int {
+	for idx++; idx < len(s); idx++ {
+		switch s[idx] {
+		case '\\':
+			idx++
+		case '"':
+			return idx + 1
+		}
+	}
+	return len(s)
+}
+
+func skipJSONSpaces(s string, idx int) int {
+	for idx < len(s) && strings.IndexByte(" \t\r\n", s[idx]) != -1 {
+		idx++
+	}
+	return idx
+}
+
+// skipJSONValue returns the position after the value which starts at idx
+func skipJSONValue(s string, idx int) int {
+	if idx >= len(s) {
+		return idx
+	}
+	switch s[idx] {
+	case '"':
+		return skipJSONString(s, idx)
+	case '{', '[':
+		depth := 0
+		for idx < len(s) {
+			switch s[idx] {
+			case '"':
+				idx = skipJSONString(s, idx)
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return idx + 1
+				}
+			}
+			idx++
+		}
+		return len(s)
+	}
+	for idx < len(s) && strings.IndexByte(",}] \t\r\n", s[idx]) == -1 {
+		idx++
+	}
+	return idx
+}
+
+// isLuhnValid checks the checksum of a card number (digits with optional spaces and dashes)
+func isLuhnValid(number string) bool {
+	var sum, count int
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		count++
+		double = !double
+	}
+	return count >= 13 && sum%10 == 0
+}
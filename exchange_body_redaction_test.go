@@ -0,0 +1,167 @@
+package echolog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	for _, tc := range []struct {
+		selector string
+		steps    []jsonPathStep
+		ok       bool
+	}{
+		{`$.password`, []jsonPathStep{{key: `password`, index: -1}}, true},
+		{`$.user.password`, []jsonPathStep{{key: `user`, index: -1}, {key: `password`, index: -1}}, true},
+		{`$..card_number`, []jsonPathStep{{key: `card_number`, index: -1, recursive: true}}, true},
+		{`$.items[*].token`, []jsonPathStep{{key: `items`, index: -1}, {key: `*`, index: -1}, {key: `token`, index: -1}}, true},
+		{`$.items[2]`, []jsonPathStep{{key: `items`, index: -1}, {index: 2}}, true},
+		{`$['a b']`, []jsonPathStep{{key: `a b`, index: -1}}, true},
+		{`$.tokens.*`, []jsonPathStep{{key: `tokens`, index: -1}, {key: `*`, index: -1}}, true},
+		{`$..[0]`, []jsonPathStep{{index: 0, recursive: true}}, true},
+		{`password`, nil, false},
+		{`$`, nil, false},
+		{`$.`, nil, false},
+		{`$.items[`, nil, false},
+		{`$.items[-1]`, nil, false},
+		{`$.a[x]`, nil, false},
+	} {
+		steps, ok := parseJSONPath(tc.selector)
+		if ok != tc.ok || !reflect.DeepEqual(steps, tc.steps) {
+			t.Errorf(`parseJSONPath(%q) = %+v, %v; expected %+v, %v`, tc.selector, steps, ok, tc.steps, tc.ok)
+		}
+	}
+}
+
+func TestIsLuhnValid(t *testing.T) {
+	for _, tc := range []struct {
+		number string
+		valid  bool
+	}{
+		{`4111111111111111`, true},
+		{`4111 1111 1111 1111`, true},
+		{`4111-1111-1111-1111`, true},
+		{`5500000000000004`, true},
+		{`4111111111111112`, false},
+		{`1234567890123456`, false},
+		{`0000`, false}, // Too short
+		{`4111a11111111111`, false},
+		{``, false},
+	} {
+		if valid := isLuhnValid(tc.number); valid != tc.valid {
+			t.Errorf(`isLuhnValid(%q) = %v; expected %v`, tc.number, valid, tc.valid)
+		}
+	}
+}
+
+func TestBodyRedactorRedact(t *testing.T) {
+	defaultRedactor := newBodyRedactor(BodyRedactionOptions{}, nil)
+	customRedactor := newBodyRedactor(BodyRedactionOptions{
+		DisableDefaults: true,
+		Fields:          []string{`$.items[*].token`, `$.tokens.*`},
+		Mask:            `***`,
+	}, nil)
+	keylessRedactor := newBodyRedactor(BodyRedactionOptions{
+		DisableDefaults: true,
+		Fields:          []string{`$[0]`},
+	}, nil)
+
+	for _, tc := range []struct {
+		name        string
+		redactor    *bodyRedactor
+		body        string
+		contentType string
+		expected    string
+		ok          bool
+	}{
+		{
+			name:        `nested password`,
+			redactor:    defaultRedactor,
+			body:        `{"user":{"password":"hunter2","name":"bob"}}`,
+			contentType: `application/json`,
+			expected:    `{"user":{"name":"bob","password":"[REDACTED]"}}`,
+			ok:          true,
+		},
+		{
+			name:        `nothing to redact`,
+			redactor:    defaultRedactor,
+			body:        `{"b": 1, "a": {"z": [1, 2]}}`,
+			contentType: `application/json`,
+			expected:    `{"b": 1, "a": {"z": [1, 2]}}`,
+			ok:          true,
+		},
+		{
+			name:        `email in a string value`,
+			redactor:    defaultRedactor,
+			body:        `{"contact":"write to bob@example.com"}`,
+			contentType: `application/json`,
+			expected:    `{"contact":"write to [REDACTED:email]"}`,
+			ok:          true,
+		},
+		{
+			name:        `card number in a text`,
+			redactor:    defaultRedactor,
+			body:        `card 4111 1111 1111 1111, not 1234567890123456`,
+			contentType: `text/plain`,
+			expected:    `card [REDACTED:card_number], not 1234567890123456`,
+			ok:          true,
+		},
+		{
+			name:        `truncated JSON`,
+			redactor:    defaultRedactor,
+			body:        `{"user":{"password":"hunter2"},"data":"aaaaaaaaaaaa`,
+			contentType: `application/json`,
+			expected:    `{"user":{"password":"[REDACTED]"},"data":"aaaaaaaaaaaa`,
+			ok:          true,
+		},
+		{
+			name:        `truncated in the middle of a secret`,
+			redactor:    defaultRedactor,
+			body:        `{"a":1,"secret":"hunt`,
+			contentType: `application/json`,
+			expected:    `{"a":1,"secret":"[REDACTED]"`,
+			ok:          true,
+		},
+		{
+			name:        `invalid JSON without a content type`,
+			redactor:    defaultRedactor,
+			body:        `{"password": {"old": "a", "new": "b"}, "x": }`,
+			contentType: ``,
+			expected:    `{"password": "[REDACTED]", "x": }`,
+			ok:          true,
+		},
+		{
+			name:        `array and wildcard selectors`,
+			redactor:    customRedactor,
+			body:        `{"items":[{"token":"a"},{"token":"b","id":1}],"tokens":{"x":"y"}}`,
+			contentType: `application/json`,
+			expected:    `{"items":[{"token":"***"},{"id":1,"token":"***"}],"tokens":{"x":"***"}}`,
+			ok:          true,
+		},
+		{
+			name:        `truncated JSON with a wildcard selector`,
+			redactor:    customRedactor,
+			body:        `{"tokens":{"x":"y"},"items":[{"token":"a"`,
+			contentType: `application/json`,
+			expected:    `{"tokens":"***","items":[{"token":"***"`,
+			ok:          true,
+		},
+		{
+			name:        `truncated JSON with a keyless selector`,
+			redactor:    keylessRedactor,
+			body:        `["secret", "`,
+			contentType: `application/json`,
+			expected:    ``,
+			ok:          false,
+		},
+	} {
+		redacted, ok := tc.redactor.redact(tc.body, tc.contentType)
+		if redacted != tc.expected || ok != tc.ok {
+			t.Errorf("%v: redact(%q) =\n%q, %v; expected\n%q, %v", tc.name, tc.body, redacted, ok, tc.expected, tc.ok)
+		}
+		if ok && strings.Contains(redacted, `hunter2`) {
+			t.Errorf(`%v: the password is not redacted`, tc.name)
+		}
+	}
+}
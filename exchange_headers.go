@@ -0,0 +1,148 @@
+package echolog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"github.com/trafficstars/echo/engine"
+)
+
+// RedactStrategy defines what to do with a value of a sensitive HTTP header
+type RedactStrategy int
+
+const (
+	// RedactMask replaces the value with a mask (see HeaderRedactionOptions.Mask)
+	RedactMask RedactStrategy = iota
+
+	// RedactHash replaces the value with a (keyed) SHA-256 hash of it. It allows
+	// to correlate requests with the same token without storing the token itself.
+	RedactHash
+
+	// RedactDrop removes the header from the log entry completely
+	RedactDrop
+)
+
+const (
+	defaultRedactMask = `[REDACTED]`
+	redactHashPrefix  = `sha256:`
+	redactHashLen     = 16 // The amount of hex characters of the hash to be logged
+)
+
+// DefaultRedactedHeaders is the list of header name patterns which are redacted
+// unless HeaderRedactionOptions.DisableDefaults is set.
+var DefaultRedactedHeaders = []string{
+	`Authorization`,
+	`Proxy-Authorization`,
+	`Cookie`,
+	`Set-Cookie`,
+	`X-Api-Key`,
+	`X-Auth-Token`,
+	`X-Access-Token`,
+	`X-Csrf-Token`,
+	`X-Xsrf-Token`,
+	`*-Api-Key`,
+	`*-Secret`,
+	`*-Password`,
+}
+
+// HeaderRedactionOptions configures redaction of HTTP headers in exchange logs
+type HeaderRedactionOptions struct {
+	Headers         []string       // Case-insensitive glob patterns (see path.Match) of headers to be redacted in addition to DefaultRedactedHeaders
+	DisableDefaults bool           // Do not redact DefaultRedactedHeaders
+	Strategy        RedactStrategy // What to do with values of matched headers
+	Mask            string         // The replacement for RedactMask, "[REDACTED]" by default
+	HashKey         []byte         // A key for HMAC-SHA256 used by RedactHash. It's recommended to set it, otherwise short tokens could be brute-forced by their hashes
+}
+
+type headerRedactor struct {
+	patterns []string
+	strategy RedactStrategy
+	mask     string
+	hashKey  []byte
+}
+
+func newHeaderRedactor(opts HeaderRedactionOptions, sink Sink) *headerRedactor {
+	r := &headerRedactor{
+		strategy: opts.Strategy,
+		mask:     opts.Mask,
+		hashKey:  opts.HashKey,
+	}
+	if r.mask == `` {
+		r.mask = defaultRedactMask
+	}
+
+	var patterns []string
+	if !opts.DisableDefaults {
+		patterns = append(patterns, DefaultRedactedHeaders...)
+	}
+	patterns = append(patterns, opts.Headers...)
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ``); err != nil {
+			writeSinkf(sink, SinkLevelError, `Invalid header redaction pattern "%v": %v`, pattern, err)
+			continue
+		}
+		r.patterns = append(r.patterns, pattern)
+	}
+
+	return r
+}
+
+func (r *headerRedactor) isSensitive(headerName string) bool {
+	if r == nil || len(r.patterns) == 0 {
+		return false
+	}
+	headerName = strings.ToLower(headerName)
+	for _, pattern := range r.patterns {
+		if matched, _ := path.Match(pattern, headerName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *headerRedactor) hash(value string) string {
+	var sum []byte
+	if len(r.hashKey) > 0 {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256([]byte(value))
+		sum = h[:]
+	}
+	return redactHashPrefix + hex.EncodeToString(sum)[:redactHashLen]
+}
+
+// redact returns the value to be logged for the header and false if the
+// header should not be logged at all
+func (r *headerRedactor) redact(headerName, value string) (string, bool) {
+	if !r.isSensitive(headerName) {
+		return value, true
+	}
+	switch r.strategy {
+	case RedactHash:
+		return r.hash(value), true
+	case RedactDrop:
+		return ``, false
+	default:
+		return r.mask, true
+	}
+}
+
+// getHeaders extracts HTTP headers from an engine.Request (or engine.Response)
+// with sensitive values redacted
+func getHeaders(headerObj engine.Header, redactor *headerRedactor) map[string]string {
+	headers := map[string]string{}
+	for _, k := range headerObj.Keys() {
+		if v, ok := redactor.redact(k, headerObj.Get(k)); ok {
+			headers[k] = v
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,117 @@
+package echolog
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/trafficstars/echo/engine"
+	"github.com/trafficstars/echo/engine/fasthttp"
+)
+
+// requestBodyRecorder captures a request body for exchange logs without
+// consuming it: it's installed before the handler is called and records
+// everything the handler reads.
+type requestBodyRecorder struct {
+	source        io.Reader
+	capturer      *bodyCapturer
+	contentLength int64
+	isEOF         bool
+
+	// inMemory is set if the engine keeps the whole body in memory
+	// (fasthttp). Such bodies could be read any amount of times, so
+	// there's no need to tee them.
+	inMemory   []byte
+	isInMemory bool
+
+	result *capturedBody
+}
+
+// newRequestBodyRecorder installs a recorder to the request, it should
+// be called before the request is passed to the handler
+func newRequestBodyRecorder(req engine.Request, limit int, withHash bool) *requestBodyRecorder {
+	r := &requestBodyRecorder{
+		capturer:      newBodyCapturer(limit, withHash),
+		contentLength: req.ContentLength(),
+	}
+
+	if fastReq, ok := req.(*fasthttp.Request); ok {
+		r.inMemory = fastReq.Request.Body()
+		r.isInMemory = true
+		return r
+	}
+
+	r.source = req.Body()
+	if r.source == nil {
+		r.isEOF = true
+		return r
+	}
+	req.SetBody(r)
+	return r
+}
+
+// Read implements io.Reader
+func (r *requestBodyRecorder) Read(p []byte) (int, error) {
+	n, err := r.source.Read(p)
+	r.capturer.Write(p[:n])
+	if err == io.EOF {
+		r.isEOF = true
+	}
+	return n, err
+}
+
+// body returns the captured body. It reads the rest of the body which was
+// not consumed by the handler (if hashing is not required, then only till
+// the limit), so it should be called after the handler is finished.
+func (r *requestBodyRecorder) body() capturedBody {
+	if r == nil {
+		return capturedBody{}
+	}
+	if r.result != nil {
+		return *r.result
+	}
+	if r.isInMemory {
+		body := captureBodyBytes(r.inMemory, r.capturer.limit, r.capturer.hash != nil)
+		r.result = &body
+		return body
+	}
+
+	if !r.isEOF {
+		var remaining io.Reader = r
+		if r.capturer.hash == nil && r.capturer.limit >= 0 {
+			left := r.capturer.limit - len(r.capturer.data)
+			if left < 0 {
+				left = 0
+			}
+			remaining = io.LimitReader(r, int64(left)+1)
+		}
+		io.Copy(ioutil.Discard, remaining)
+	}
+
+	body := r.capturer.result()
+	if !r.isEOF && r.contentLength > body.length {
+		// We've read the body partially, so trust the declared length
+		body.length = r.contentLength
+	}
+	if body.length > int64(len(body.data)) {
+		body.truncated = true
+	}
+	r.result = &body
+	return body
+}
+
+// peek returns the body captured so far without reading the rest of it,
+// so it's safe to be called while the handler is still consuming the body.
+// A partially read body is marked as truncated.
+func (r *requestBodyRecorder) peek() capturedBody {
+	if r == nil {
+		return capturedBody{}
+	}
+	if r.result != nil || r.isInMemory {
+		return r.body()
+	}
+	body := r.capturer.result()
+	if !r.isEOF {
+		body.truncated = true
+	}
+	return body
+}
@@ -0,0 +1,97 @@
+package echolog
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/trafficstars/echo/engine"
+	"github.com/trafficstars/echo/engine/fasthttp"
+	"github.com/trafficstars/echo/engine/standard"
+)
+
+// responseBodyRecorder captures a response for exchange logs.
+//
+// fasthttp keeps the whole response in memory until the handler is finished,
+// so it's just read from there. For other engines (like engine/standard)
+// the recorder is installed as the writer of the response and tees
+// everything written by the handler. The status and headers are
+// remembered at the moment of the first write, because that's what was
+// actually sent to the client.
+type responseBodyRecorder struct {
+	response   engine.Response
+	writer     io.Writer
+	capturer   *bodyCapturer
+	isInMemory bool
+
+	isWritten     bool
+	writtenStatus int
+	writtenHeader engine.Header
+}
+
+// newResponseBodyRecorder installs a recorder to the response, it should
+// be called before the response is passed to the handler
+func newResponseBodyRecorder(res engine.Response, limit int, withHash bool) *responseBodyRecorder {
+	r := &responseBodyRecorder{
+		response: res,
+		capturer: newBodyCapturer(limit, withHash),
+	}
+
+	if _, ok := res.(*fasthttp.Response); ok {
+		r.isInMemory = true
+		return r
+	}
+
+	r.writer = res.Writer()
+	res.SetWriter(r)
+	return r
+}
+
+// Write implements io.Writer
+func (r *responseBodyRecorder) Write(b []byte) (int, error) {
+	if !r.isWritten {
+		r.isWritten = true
+		r.writtenStatus = r.response.Status()
+		r.writtenHeader = snapshotHeader(r.response.Header())
+	}
+	n, err := r.writer.Write(b)
+	r.capturer.Write(b[:n])
+	return n, err
+}
+
+// snapshotHeader copies headers to be not affected by further modifications
+func snapshotHeader(header engine.Header) engine.Header {
+	snapshot := http.Header{}
+	for _, k := range header.Keys() {
+		snapshot.Set(k, header.Get(k))
+	}
+	return &standard.Header{Header: snapshot}
+}
+
+func (r *responseBodyRecorder) body() capturedBody {
+	if r == nil {
+		return capturedBody{}
+	}
+	if r.isInMemory {
+		fastRes := r.response.(*fasthttp.Response)
+		return captureBodyBytes(fastRes.RequestCtx.Response.Body(), r.capturer.limit, r.capturer.hash != nil)
+	}
+	return r.capturer.result()
+}
+
+// status returns the status sent to the client (the current status of
+// the response if the recorder is not installed or nothing is written)
+func (r *responseBodyRecorder) status(res engine.Response) int {
+	if r == nil || !r.isWritten {
+		return res.Status()
+	}
+	return r.writtenStatus
+}
+
+// header returns the headers sent to the client (the current headers of
+// the response if the recorder is not installed or nothing is written)
+func (r *responseBodyRecorder) header(res engine.Response) engine.Header {
+	if r == nil || !r.isWritten {
+		return res.Header()
+	}
+	return r.writtenHeader
+}
@@ -0,0 +1,51 @@
+package echolog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FatalPolicy defines what Fatal* methods of LoggerContextLogger do
+type FatalPolicy int
+
+const (
+	// FatalPolicyExit logs the message at FATAL level and exits the process (the default)
+	FatalPolicyExit FatalPolicy = iota
+
+	// FatalPolicyPanic logs the message at ERROR level and panics, so the panic
+	// could be handled by the panic recovery (see RecoverOptions) and only the
+	// request fails
+	FatalPolicyPanic
+
+	// FatalPolicyContinue logs the message at ERROR level and continues the execution
+	FatalPolicyContinue
+)
+
+// fatal handles a fatal message according to the FatalPolicy
+func (ctxLogger *LoggerContextLogger) fatal(sink Sink, message string) {
+	switch ctxLogger.fatalPolicy {
+	case FatalPolicyPanic:
+		sink.WithFields(Fields{`fatal`: true}).Write(SinkLevelError, message)
+		panic(message)
+	case FatalPolicyContinue:
+		sink.WithFields(Fields{`fatal`: true}).Write(SinkLevelError, message)
+	default:
+		sink.Write(SinkLevelFatal, message)
+		// Let asynchronous writers to flush before the exit
+		if ctxLogger.fatalExitHook != nil {
+			ctxLogger.fatalExitHook()
+		}
+		if exiter, ok := sink.(SinkExiter); ok {
+			// It could be overridden (for example logrus.Logger.ExitFunc in tests)
+			exiter.Exit(1)
+			return
+		}
+		os.Exit(1)
+	}
+}
+
+// sprintln is fmt.Sprintln without the trailing new line (the same as in logrus)
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
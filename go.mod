@@ -1,9 +1,22 @@
 module github.com/trafficstars/echolog
 
-go 1.13
+go 1.21
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/labstack/gommon v0.3.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/trafficstars/echo v1.2.1-0.20210118175209-73964bf4328a
+	github.com/valyala/fasttemplate v1.0.1
+)
+
+require (
+	github.com/klauspost/compress v1.8.2 // indirect
+	github.com/klauspost/cpuid v1.2.1 // indirect
+	github.com/mattn/go-colorable v0.1.2 // indirect
+	github.com/mattn/go-isatty v0.0.9 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.9.0 // indirect
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
+	golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
 )
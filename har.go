@@ -0,0 +1,301 @@
+package echolog
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HAR (HTTP Archive) 1.2 structures, see http://www.softwareishard.com/blog/har-12-spec/
+
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"` // The request ID
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// DefaultHARMaxEntries is the default value of HARWriter.MaxEntries
+const DefaultHARMaxEntries = 1000
+
+// HARWriter collects exchanges (see Options.HARWriter) as HAR entries. The
+// result could be loaded to browser dev tools or replayed by HAR-compatible tools.
+//
+// It's a debugging tool: entries are kept in memory (limited by MaxEntries)
+// and the archive is written on demand (see WriteTo and Flush).
+type HARWriter struct {
+	MaxEntries int // Only the last MaxEntries are kept (DefaultHARMaxEntries if zero, no limit if negative)
+
+	lock     sync.Mutex
+	entries  []HAREntry
+	filePath string
+	fileLock sync.Mutex
+}
+
+// NewHARWriter returns an in-memory collector of HAR entries
+func NewHARWriter() *HARWriter {
+	return &HARWriter{}
+}
+
+// NewHARFileWriter returns a collector of HAR entries which writes them to
+// the file at filePath on Flush()
+func NewHARFileWriter(filePath string) *HARWriter {
+	return &HARWriter{
+		filePath: filePath,
+	}
+}
+
+// Add adds an entry to the archive
+func (w *HARWriter) Add(entry HAREntry) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	maxEntries := w.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultHARMaxEntries
+	}
+
+	w.entries = append(w.entries, entry)
+	if maxEntries > 0 && len(w.entries) > maxEntries {
+		w.entries = append(w.entries[:0], w.entries[len(w.entries)-maxEntries:]...)
+	}
+	return nil
+}
+
+// Entries returns a copy of all collected entries
+func (w *HARWriter) Entries() []HAREntry {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	entries := make([]HAREntry, len(w.entries))
+	copy(entries, w.entries)
+	return entries
+}
+
+// Reset removes all collected entries
+func (w *HARWriter) Reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.entries = nil
+}
+
+// har returns the archive with a copy of the collected entries
+func (w *HARWriter) har() HAR {
+	entries := w.Entries()
+	return HAR{
+		Log: HARLog{
+			Version: `1.2`,
+			Creator: HARCreator{
+				Name:    `github.com/trafficstars/echolog`,
+				Version: `1`,
+			},
+			Entries: entries,
+		},
+	}
+}
+
+// WriteTo writes the whole archive as a JSON document
+func (w *HARWriter) WriteTo(out io.Writer) (int64, error) {
+	b, err := json.MarshalIndent(w.har(), ``, `  `)
+	if err != nil {
+		return 0, err
+	}
+	n, err := out.Write(b)
+	return int64(n), err
+}
+
+// Flush atomically replaces the file (see NewHARFileWriter) with the current
+// archive. It does nothing if the file is not set.
+func (w *HARWriter) Flush() error {
+	if w.filePath == `` {
+		return nil
+	}
+	w.fileLock.Lock()
+	defer w.fileLock.Unlock()
+
+	b, err := json.MarshalIndent(w.har(), ``, `  `)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(w.filePath), filepath.Base(w.filePath)+`.tmp`)
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(b); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), w.filePath)
+}
+
+func toHARNameValues(m map[string]string) []HARNameValue {
+	r := make([]HARNameValue, 0, len(m))
+	for k, v := range m {
+		r = append(r, HARNameValue{Name: k, Value: v})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r
+}
+
+func toHARQueryString(query string) []HARNameValue {
+	r := []HARNameValue{}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return r
+	}
+	for k, vs := range values {
+		for _, v := range vs {
+			r = append(r, HARNameValue{Name: k, Value: v})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r
+}
+
+// harExchange is everything collected by the middleware about an exchange to build a HAR entry
+type harExchange struct {
+	c                  *LoggerContext
+	requestHeaders     map[string]string
+	requestBody        capturedBody
+	requestBodyString  string
+	responseHeaders    map[string]string
+	responseStatus     int
+	responseBody       capturedBody
+	responseBodyString string
+	isResponseBase64   bool
+}
+
+func newHAREntry(exchange harExchange) HAREntry {
+	c := exchange.c
+	req := c.Request()
+	latency := float64(time.Since(c.StartTime)) / float64(time.Millisecond)
+
+	scheme := req.Scheme()
+	if scheme == `` {
+		scheme = `http`
+	}
+	httpVersion := getRequestProtocol(req)
+
+	entry := HAREntry{
+		StartedDateTime: c.StartTime,
+		Time:            latency,
+		Request: HARRequest{
+			Method:      req.Method(),
+			URL:         scheme + `://` + req.Host() + req.URI(),
+			HTTPVersion: httpVersion,
+			Cookies:     []HARNameValue{},
+			Headers:     toHARNameValues(exchange.requestHeaders),
+			QueryString: toHARQueryString(req.URL().QueryString()),
+			HeadersSize: -1,
+			BodySize:    exchange.requestBody.length,
+		},
+		Response: HARResponse{
+			Status:      exchange.responseStatus,
+			StatusText:  http.StatusText(exchange.responseStatus),
+			HTTPVersion: httpVersion,
+			Cookies:     []HARNameValue{},
+			Headers:     toHARNameValues(exchange.responseHeaders),
+			Content: HARContent{
+				Size:     exchange.responseBody.length,
+				MimeType: exchange.responseHeaders[`Content-Type`],
+				Text:     exchange.responseBodyString,
+			},
+			RedirectURL: exchange.responseHeaders[`Location`],
+			HeadersSize: -1,
+			BodySize:    exchange.responseBody.length,
+		},
+		Timings: HARTimings{
+			Wait: latency,
+		},
+		Comment: c.GetRequestID(),
+	}
+
+	if exchange.isResponseBase64 {
+		entry.Response.Content.Encoding = `base64`
+	}
+
+	if exchange.requestBody.length > 0 {
+		entry.Request.PostData = &HARPostData{
+			MimeType: req.Header().Get(`Content-Type`),
+			Text:     exchange.requestBodyString,
+		}
+	}
+
+	return entry
+}
@@ -0,0 +1,151 @@
+package echolog
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	labstacklog "github.com/labstack/gommon/log"
+)
+
+// JobLoggerOptions configures a logger of a background job (see NewJobLogger)
+type JobLoggerOptions struct {
+	Options                       // The same options as for Middleware (only the logging related ones are used)
+	ID            string          // An inbound ID of the job (for example from a header of a queue message), a random ID is generated if empty
+	LogLevel      labstacklog.Lvl // Enforces the log level of the job (otherwise it's chosen the same way as for requests: DefaultLogLevel and DebugLogLevelFraction)
+	IsLogLevelSet bool            // Enforce LogLevel even if it's zero (TRACE)
+}
+
+// JobLogger is a logger of a single run of a cron job, a queue consumer,
+// a CLI command, etc. It's set up the same way as a logger of an HTTP request:
+// with a request ID, a sampled debug level, sampled stack traces, cached logs
+// and the start time.
+type JobLogger struct {
+	LoggerContextLogger
+
+	name        string
+	releaseOnce sync.Once
+}
+
+// JobLoggerFactory creates loggers of runs of jobs with the same options.
+// The options are processed (the sink is created, etc) only once, so it should
+// be used for jobs which are run often (like queue consumers).
+type JobLoggerFactory struct {
+	gen           *loggerContextGenerator
+	logLevel      labstacklog.Lvl
+	isLogLevelSet bool
+}
+
+// NewJobLoggerFactory returns a factory of job loggers. opts.ID is ignored,
+// IDs are passed to New. The factory is affected by SetDebugLogLevelFraction,
+// SetDefaultLogLevel, etc (the same as Middleware), so it's never collected.
+func NewJobLoggerFactory(opts JobLoggerOptions) *JobLoggerFactory {
+	return &JobLoggerFactory{
+		gen:           newLoggerContextGenerator(opts.Options),
+		logLevel:      opts.LogLevel,
+		isLogLevelSet: opts.IsLogLevelSet || opts.LogLevel != labstacklog.Lvl(0),
+	}
+}
+
+// NewJobLogger returns a logger for a run of the job. Release() should be
+// called when the job is finished.
+//
+// The options are processed on each call, use NewJobLoggerFactory for jobs
+// which are run often.
+func NewJobLogger(name string, opts JobLoggerOptions) *JobLogger {
+	// The generator is not registered, so it's collected with the job logger
+	f := &JobLoggerFactory{
+		gen:           buildLoggerContextGenerator(opts.Options),
+		logLevel:      opts.LogLevel,
+		isLogLevelSet: opts.IsLogLevelSet || opts.LogLevel != labstacklog.Lvl(0),
+	}
+	return f.New(name, opts.ID)
+}
+
+// New returns a logger for a run of the job. If id is empty then a random
+// ID is generated. Release() should be called when the job is finished.
+func (f *JobLoggerFactory) New(name string, id string) *JobLogger {
+	gen := f.gen
+
+	requestID := id
+	if requestID == `` {
+		requestID = gen.generateRandomRequestID()
+	}
+
+	logLevel := gen.defaultLogLevel
+	if rand.Float32() < gen.debugLogLevelFraction {
+		logLevel = labstacklog.DEBUG
+	}
+	if f.isLogLevelSet {
+		logLevel = f.logLevel
+	}
+
+	job := &JobLogger{
+		LoggerContextLogger: LoggerContextLogger{
+			requestID: requestID,
+			sink: gen.sink.WithFields(Fields{
+				`request_id`: requestID,
+				`job`:        name,
+			}),
+			LogLevel:            logLevel,
+			IsStackTraceEnabled: rand.Float32() < gen.enableStackTraceFraction,
+			StartTime:           time.Now(),
+			fatalPolicy:         gen.fatalPolicy,
+			fatalExitHook:       gen.fatalExitHook,
+		},
+		name: name,
+	}
+	if gen.cacheLogs {
+		job.cache = &cache{data: make([]string, 0, 0)}
+	}
+	job.lineBudget = newLineBudget(gen.maxLinesPerRequest, gen.maxBytesPerRequest)
+	return job
+}
+
+// Name returns the name of the job
+func (job *JobLogger) Name() string {
+	return job.name
+}
+
+// GetRequestID returns the ID of the run of the job
+func (job *JobLogger) GetRequestID() string {
+	return job.requestID
+}
+
+// Flush writes the cached logs (see Options.CacheLogs) regardless of the log
+// level and clears the cache. It's useful to report a failure of a long job.
+func (job *JobLogger) Flush() {
+	cachedLogs := job.cache.Flush()
+	if len(cachedLogs) == 0 {
+		return
+	}
+	job.sink.WithFields(Fields{
+		`what`:        `job_cached_logs`,
+		`cached_logs`: cachedLogs,
+	}).Write(SinkLevelInfo, `cached logs of the job`)
+}
+
+// Release finishes the job: writes the "job_finished" entry with the duration
+// of the job regardless of the log level (like the access log of a request).
+// If err is not nil then it's logged at ERROR level together with the cached
+// logs. Only the first call has an effect.
+func (job *JobLogger) Release(err error) {
+	job.releaseOnce.Do(func() {
+		defer job.lineBudget.writeSummary(job.sink)
+
+		fields := Fields{
+			`what`:    `job_finished`,
+			`latency`: time.Since(job.StartTime),
+		}
+		if err == nil {
+			writeSinkf(job.sink.WithFields(fields), SinkLevelInfo, `job "%v" finished`, job.name)
+			return
+		}
+
+		fields[`error`] = err.Error()
+		if cachedLogs := job.cache.Flush(); len(cachedLogs) > 0 {
+			fields[`cached_logs`] = cachedLogs
+		}
+		writeSinkf(job.sink.WithFields(fields), SinkLevelError, `job "%v" failed: %v`, job.name, err)
+	})
+}
@@ -0,0 +1,113 @@
+package echolog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	labstacklog "github.com/labstack/gommon/log"
+)
+
+// TRACE is the level below DEBUG for very chatty output (for example parameters
+// of SQL queries), see Trace* methods of LoggerContextLogger.
+//
+// It's the zero value of labstacklog.Lvl, which means "not set" in options,
+// so options accepting it have flags to set it explicitly (for example
+// Options.IsDefaultLogLevelSet and CustomLevel.IsSeveritySet). It could be
+// enabled per request as well ("x_log_level=trace") or with SetLevel().
+const TRACE = labstacklog.Lvl(0)
+
+// CustomLevel is a named level registered with RegisterLevel
+type CustomLevel struct {
+	Severity      labstacklog.Lvl // Messages are filtered by the log level of the request as if they had this level (and written with it), TRACE..ERROR (required)
+	IsSeveritySet bool            // Should be set if Severity is TRACE (zero)
+	Sink          Sink            // If set then messages are written to this sink (with the request ID) instead of the logger of the request
+}
+
+var customLevels = struct {
+	sync.RWMutex
+	m map[string]CustomLevel
+}{
+	m: map[string]CustomLevel{},
+}
+
+// RegisterLevel registers a custom named level (for example "audit" or
+// "security") to be used with LoggerContextLogger.Log and Logf. Messages of
+// the level get the field "log_level_name" and could be routed to a separate Sink.
+func RegisterLevel(name string, level CustomLevel) error {
+	name = strings.ToLower(name)
+	if name == `` {
+		return fmt.Errorf(`empty level name`)
+	}
+	if TryParseLogLevel(name, labstacklog.OFF+1) != labstacklog.OFF+1 {
+		return fmt.Errorf(`level "%v" is a built-in level`, name)
+	}
+	if level.Severity == labstacklog.Lvl(0) && !level.IsSeveritySet {
+		return fmt.Errorf(`the severity of level "%v" is not set (IsSeveritySet should be set for TRACE)`, name)
+	}
+	if level.Severity > labstacklog.ERROR {
+		return fmt.Errorf(`invalid severity of level "%v": %v`, name, level.Severity)
+	}
+
+	customLevels.Lock()
+	customLevels.m[name] = level
+	customLevels.Unlock()
+	return nil
+}
+
+// UnregisterLevel removes a custom level registered with RegisterLevel
+func UnregisterLevel(name string) {
+	customLevels.Lock()
+	delete(customLevels.m, strings.ToLower(name))
+	customLevels.Unlock()
+}
+
+func getCustomLevel(name string) (CustomLevel, bool) {
+	customLevels.RLock()
+	level, ok := customLevels.m[strings.ToLower(name)]
+	customLevels.RUnlock()
+	return level, ok
+}
+
+func getSinkLevel(level labstacklog.Lvl) SinkLevel {
+	switch level {
+	case TRACE:
+		return SinkLevelTrace
+	case labstacklog.DEBUG:
+		return SinkLevelDebug
+	case labstacklog.INFO:
+		return SinkLevelInfo
+	case labstacklog.WARN:
+		return SinkLevelWarn
+	}
+	return SinkLevelError
+}
+
+// Log writes a message of a custom level (see RegisterLevel)
+func (ctxLogger *LoggerContextLogger) Log(levelName string, args ...interface{}) {
+	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
+	ctxLogger.writeCustomLevel(levelName, fmt.Sprint(addSpacesToArgs(args)...))
+}
+
+// Logf writes a formatted message of a custom level (see RegisterLevel)
+func (ctxLogger *LoggerContextLogger) Logf(levelName string, format string, args ...interface{}) {
+	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
+	ctxLogger.writeCustomLevel(levelName, fmt.Sprintf(format, args...))
+}
+
+func (ctxLogger *LoggerContextLogger) writeCustomLevel(levelName string, message string) {
+	level, ok := getCustomLevel(levelName)
+	if !ok {
+		ctxLogger.WithField(`log_level_name`, levelName).Errorf(`Unknown log level "%v": %v`, levelName, message)
+		return
+	}
+	if ctxLogger.LogLevel > level.Severity {
+		return
+	}
+
+	sink := ctxLogger.getPreparedSink()
+	if level.Sink != nil {
+		sink = level.Sink.WithFields(Fields{`request_id`: ctxLogger.requestID})
+	}
+	sink.WithFields(Fields{`log_level_name`: levelName}).Write(getSinkLevel(level.Severity), message)
+}
@@ -0,0 +1,150 @@
+package echolog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// lineBudget limits the amount of log lines of a request (see Options.MaxLinesPerRequest
+// and Options.MaxBytesPerRequest). It's shared by all copies of the logger of the request
+// except detached ones (see LoggerContextLogger.Detach), they have their own budgets.
+type lineBudget struct {
+	sync.Mutex
+	maxLines          int
+	maxBytes          int
+	isDetached        bool // There's no end of a detached logger, so the exceeding is reported immediately
+	lines             int
+	bytes             int
+	isExceeded        bool
+	suppressedLines   int
+	suppressedBytes   int
+	firstSuppressedAt time.Time
+}
+
+func newLineBudget(maxLines, maxBytes int) *lineBudget {
+	if maxLines <= 0 && maxBytes <= 0 {
+		return nil
+	}
+	return &lineBudget{
+		maxLines: maxLines,
+		maxBytes: maxBytes,
+	}
+}
+
+// detach returns a budget with the same limits for a detached logger
+func (b *lineBudget) detach() *lineBudget {
+	if b == nil {
+		return nil
+	}
+	return &lineBudget{
+		maxLines:   b.maxLines,
+		maxBytes:   b.maxBytes,
+		isDetached: true,
+	}
+}
+
+// allow returns true if the message fits into the budget. Once the budget
+// is exceeded all further messages are suppressed (and counted).
+// isExceedingReportNeeded is true for the first suppressed message of
+// a detached budget.
+func (b *lineBudget) allow(message string) (isAllowed bool, isExceedingReportNeeded bool) {
+	if b == nil {
+		return true, false
+	}
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.isExceeded {
+		b.isExceeded = (b.maxLines > 0 && b.lines+1 > b.maxLines) ||
+			(b.maxBytes > 0 && b.bytes+len(message) > b.maxBytes)
+	}
+	if !b.isExceeded {
+		b.lines++
+		b.bytes += len(message)
+		return true, false
+	}
+
+	if b.suppressedLines == 0 {
+		b.firstSuppressedAt = time.Now()
+	}
+	b.suppressedLines++
+	b.suppressedBytes += len(message)
+	return false, b.isDetached && b.suppressedLines == 1
+}
+
+// writeSummary writes a line about suppressed messages (if there were any)
+func (b *lineBudget) writeSummary(sink Sink) {
+	if b == nil {
+		return
+	}
+	b.Lock()
+	suppressedLines, suppressedBytes, firstSuppressedAt := b.suppressedLines, b.suppressedBytes, b.firstSuppressedAt
+	b.Unlock()
+	if suppressedLines == 0 {
+		return
+	}
+
+	sink.WithFields(Fields{
+		`what`:                `log_lines_suppressed`,
+		`suppressed_lines`:    suppressedLines,
+		`suppressed_bytes`:    suppressedBytes,
+		`first_suppressed_at`: firstSuppressedAt,
+	}).Write(SinkLevelWarn, fmt.Sprintf(`suppressed %d lines, first suppressed at %v`, suppressedLines, firstSuppressedAt.Format(time.RFC3339Nano)))
+}
+
+// writeExceedingReport writes a line that further messages are suppressed
+// (it's used instead of the summary for detached budgets)
+func (b *lineBudget) writeExceedingReport(sink Sink) {
+	sink.WithFields(Fields{
+		`what`:      `log_lines_suppressed`,
+		`max_lines`: b.maxLines,
+		`max_bytes`: b.maxBytes,
+	}).Write(SinkLevelWarn, `the log budget of the detached logger is exceeded, further lines are suppressed`)
+}
+
+// lineBudgetSink drops messages which exceed the budget. Fatal and panic
+// messages are always written.
+type lineBudgetSink struct {
+	Sink
+	budget *lineBudget
+}
+
+func (s lineBudgetSink) WithFields(fields Fields) Sink {
+	return lineBudgetSink{
+		Sink:   s.Sink.WithFields(fields),
+		budget: s.budget,
+	}
+}
+
+func (s lineBudgetSink) Write(level SinkLevel, message string) {
+	if level >= SinkLevelFatal {
+		s.Sink.Write(level, message)
+		return
+	}
+	isAllowed, isExceedingReportNeeded := s.budget.allow(message)
+	if isExceedingReportNeeded {
+		s.budget.writeExceedingReport(s.Sink)
+	}
+	if isAllowed {
+		s.Sink.Write(level, message)
+	}
+}
+
+// SetOutput implements SinkOutputSetter
+func (s lineBudgetSink) SetOutput(w io.Writer) {
+	if outputSetter, ok := s.Sink.(SinkOutputSetter); ok {
+		outputSetter.SetOutput(w)
+	}
+}
+
+// Exit implements SinkExiter
+func (s lineBudgetSink) Exit(code int) {
+	if exiter, ok := s.Sink.(SinkExiter); ok {
+		exiter.Exit(code)
+		return
+	}
+	os.Exit(code)
+}
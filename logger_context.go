@@ -1,6 +1,7 @@
 package echolog
 
 import (
+	"fmt"
 	"io"
 	"math/rand"
 	"runtime/debug"
@@ -21,11 +22,16 @@ type echoContext = echo.Context
 
 type LoggerContextLogger struct {
 	requestID           string
-	logger              logrus.FieldLogger
+	sink                Sink
 	LogLevel            labstacklog.Lvl
 	IsStackTraceEnabled bool
 	StartTime           time.Time
 	cache               *cache
+	lineBudget          *lineBudget
+	fatalPolicy         FatalPolicy
+	fatalExitHook       func()
+	owner               *LoggerContext // The pooled context of the logger (nil if it's not bound to a request)
+	generation          uint64         // The generation of the owner when the logger was acquired
 }
 type contextLogger = LoggerContextLogger // To be able to do that as a private anonymous variable
 type ContextLogger = LoggerContextLogger // Just a shortcut
@@ -34,7 +40,12 @@ type LoggerContext struct {
 	echoContext
 	contextLogger
 
-	generator *loggerContextGenerator
+	generator            *loggerContextGenerator
+	requestBodyRecorder  *requestBodyRecorder
+	responseBodyRecorder *responseBodyRecorder
+	isExchangeLogForced  bool
+	timings              timings
+	generation           uint64 // Is incremented on each acquire and release (accessed atomically)
 }
 
 var (
@@ -49,7 +60,7 @@ func (ctx *LoggerContext) init(
 	generator *loggerContextGenerator,
 	origCtx echo.Context,
 	requestID string,
-	logger logrus.FieldLogger,
+	sink Sink,
 	logLevel labstacklog.Lvl,
 	isStackTraceEnabled bool,
 	isCachingEnabled bool,
@@ -58,21 +69,32 @@ func (ctx *LoggerContext) init(
 	ctx.generator = generator
 	ctx.echoContext = origCtx
 	ctx.requestID = requestID
-	ctx.logger = logger.WithField(`request_id`, requestID)
+	ctx.sink = sink.WithFields(Fields{`request_id`: requestID})
 	ctx.LogLevel = logLevel
 	ctx.IsStackTraceEnabled = isStackTraceEnabled
 	ctx.StartTime = startTime
+	ctx.fatalPolicy = generator.fatalPolicy
+	ctx.fatalExitHook = generator.fatalExitHook
+	ctx.owner = ctx
+	ctx.contextLogger.generation = atomic.AddUint64(&ctx.generation, 1)
+	ctx.requestBodyRecorder = nil
+	ctx.responseBodyRecorder = nil
+	ctx.isExchangeLogForced = false
+	ctx.timings.reset(startTime)
 	if isCachingEnabled {
 		ctx.cache = &cache{data: make([]string, 0, 0)}
 	}
+	ctx.lineBudget = newLineBudget(generator.maxLinesPerRequest, generator.maxBytesPerRequest)
 }
 
 func GetDefaultContextLogger() *LoggerContextLogger {
 	r := &LoggerContextLogger{
 		requestID:           `undefined`,
-		logger:              GetDefaultLogger(),
+		sink:                defaultLogrusSink,
 		LogLevel:            defaultContextLoggerSettings.defaultLogLevel,
 		IsStackTraceEnabled: rand.Float32() < defaultContextLoggerSettings.enableStackTraceFraction,
+		fatalPolicy:         defaultContextLoggerSettings.fatalPolicy,
+		fatalExitHook:       defaultContextLoggerSettings.fatalExitHook,
 	}
 
 	if rand.Float32() < defaultContextLoggerSettings.debugLogLevelFraction {
@@ -155,7 +177,7 @@ func (ctx *LoggerContext) IfShouldWriteExchangeLog(fn func()) {
 		}
 	}
 
-	if shouldWrite || ctx.LogLevel == labstacklog.DEBUG {
+	if shouldWrite || ctx.isExchangeLogForced || ctx.LogLevel <= labstacklog.DEBUG {
 		fn()
 	}
 }
@@ -176,11 +198,33 @@ func (ctxLogger LoggerContextLogger) ScopeEnableStackTrace(newEnableStackTrace b
 	return &ctxLogger
 }
 
+// Detach returns an independent copy of the logger to be used by goroutines
+// which could outlive the request (LoggerContext is reused after Release()).
+// The copy keeps the request ID, the level and the fields, shares the cached
+// logs and has the field "parent_request_id". The copy has its own limits of
+// lines (see Options.MaxLinesPerRequest), the exceeding of them is reported
+// immediately (there's no end of the copy to write a summary).
+func (ctxLogger LoggerContextLogger) Detach() *LoggerContextLogger {
+	// ctxLogger is not a pointer, so it's a copy here. The cache is
+	// safe to share: it's synchronized and is not reused by other requests.
+	ctxLogger.sink = ctxLogger.sink.WithFields(Fields{`parent_request_id`: ctxLogger.requestID})
+	ctxLogger.lineBudget = ctxLogger.lineBudget.detach()
+	ctxLogger.owner = nil
+	return &ctxLogger
+}
+
+// Fork is an alias of Detach
+func (ctxLogger LoggerContextLogger) Fork() *LoggerContextLogger {
+	return ctxLogger.Detach()
+}
+
 func (ctxLogger LoggerContextLogger) WithField(key string, value interface{}) *LoggerContextLogger {
-	ctxLogger.logger = ctxLogger.logger.WithField(key, value)
+	ctxLogger.sink = ctxLogger.sink.WithFields(Fields{key: value})
 	return &ctxLogger
 }
 
+// Fields are fields of a log message. It's an alias of logrus.Fields, so
+// WithFields accepts logrus.Fields as before.
 type Fields = logrus.Fields
 
 // WithFields create a new scope with the fields
@@ -191,14 +235,16 @@ func (ctxLogger LoggerContextLogger) WithFields(fields logrus.Fields) *LoggerCon
 
 // SetFields sets the fields within the current scope
 func (ctxLogger *LoggerContextLogger) SetFields(fields logrus.Fields) *LoggerContextLogger {
-	ctxLogger.logger = ctxLogger.logger.WithFields(fields)
+	ctxLogger.sink = ctxLogger.sink.WithFields(fields)
 	return ctxLogger
 }
 
-func (ctxLogger *LoggerContextLogger) getPreparedLogger() logrus.FieldLogger {
+func (ctxLogger *LoggerContextLogger) getPreparedSink() Sink {
 	// TODO: this's quite slow and stupid method. Fix the performance issue.
 
-	logger := ctxLogger.logger
+	ctxLogger.checkReleased()
+
+	fields := Fields{}
 	stack := string(debug.Stack())
 
 	stackLines := strings.Split(stack, "\n")
@@ -206,250 +252,277 @@ func (ctxLogger *LoggerContextLogger) getPreparedLogger() logrus.FieldLogger {
 		if line[0] != '\t' {
 			continue
 		}
-		if strings.Index(line, `echolog`) == -1 {
+		if strings.Index(line, `echolog`) == -1 && strings.Index(line, `/log/slog/`) == -1 { // Skip the frames of the logger itself and of log/slog (see Slog())
 			line = line[1:]
-			logger = logger.WithField(`line`, line)
+			fields[`line`] = line
 			break
 		}
 	}
 
 	if ctxLogger.IsStackTraceEnabled {
-		logger = logger.WithField(`stack_trace`, stack)
+		fields[`stack_trace`] = stack
 	}
 	if !ctxLogger.StartTime.IsZero() {
-		logger = logger.WithField(`request_time`, time.Since(ctxLogger.StartTime))
+		fields[`request_time`] = time.Since(ctxLogger.StartTime)
 	}
-	logger = logger.WithField(`uptime`, time.Since(startTime))
+	fields[`uptime`] = time.Since(startTime)
 
 	// Remember which log level was used by environment when we sent it
 	// Useful to find requests which enforced debug level
-	logger = logger.WithField(`ctx_logger_level`, ctxLogger.LogLevel)
+	fields[`ctx_logger_level`] = ctxLogger.LogLevel
 
-	// TODO: remove this hack:
-	atomic.StoreUint32((*uint32)(&logger.(*logrus.Entry).Level), uint32(logrus.TraceLevel))
-
-	return logger
+	sink := ctxLogger.sink.WithFields(fields)
+	if ctxLogger.lineBudget != nil {
+		sink = lineBudgetSink{Sink: sink, budget: ctxLogger.lineBudget}
+	}
+	return sink
 }
 
+func (ctxLogger *LoggerContextLogger) Tracef(format string, args ...interface{}) {
+	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
+	if ctxLogger.LogLevel > TRACE {
+		return
+	}
+	ctxLogger.getPreparedSink().Write(SinkLevelTrace, fmt.Sprintf(format, args...))
+}
 func (ctxLogger *LoggerContextLogger) Debugf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	if ctxLogger.LogLevel > labstacklog.DEBUG {
 		return
 	}
-	ctxLogger.getPreparedLogger().Debugf(format, args...)
+	ctxLogger.getPreparedSink().Write(SinkLevelDebug, fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Infof(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().Infof(format, args...)
+	ctxLogger.getPreparedSink().Write(SinkLevelInfo, fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Printf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().Printf(format, args...)
+	ctxLogger.getPreparedSink().Write(SinkLevelInfo, fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Warnf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().Warnf(format, args...)
+	ctxLogger.getPreparedSink().Write(SinkLevelWarn, fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Warningf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().Warningf(format, args...)
+	ctxLogger.getPreparedSink().Write(SinkLevelWarn, fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Errorf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	if ctxLogger.LogLevel > labstacklog.ERROR {
 		return
 	}
-	ctxLogger.getPreparedLogger().Errorf(format, args...)
+	ctxLogger.getPreparedSink().Write(SinkLevelError, fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Fatalf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().Fatalf(format, args...)
+	ctxLogger.fatal(ctxLogger.getPreparedSink(), fmt.Sprintf(format, args...))
 }
 func (ctxLogger *LoggerContextLogger) Panicf(format string, args ...interface{}) {
 	ctxLogger.cache.Putf(ctxLogger.LogLevel, format, args...)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().Panicf(format, args...)
+	ctxLogger.writePanic(ctxLogger.getPreparedSink(), fmt.Sprintf(format, args...))
+}
+func (ctxLogger *LoggerContextLogger) Trace(args ...interface{}) {
+	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
+	if ctxLogger.LogLevel > TRACE {
+		return
+	}
+	ctxLogger.getPreparedSink().Write(SinkLevelTrace, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Debug(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.DEBUG {
 		return
 	}
-	ctxLogger.getPreparedLogger().Debug(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelDebug, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Info(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().Info(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelInfo, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Print(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().Print(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelInfo, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Warn(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().Warn(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelWarn, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Warning(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().Warning(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelWarn, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Error(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.ERROR {
 		return
 	}
-	ctxLogger.getPreparedLogger().Error(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelError, fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Fatal(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().Fatal(addSpacesToArgs(args)...)
+	ctxLogger.fatal(ctxLogger.getPreparedSink(), fmt.Sprint(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Panic(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().Panic(addSpacesToArgs(args)...)
+	ctxLogger.writePanic(ctxLogger.getPreparedSink(), fmt.Sprint(addSpacesToArgs(args)...))
+}
+func (ctxLogger *LoggerContextLogger) Traceln(args ...interface{}) {
+	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
+	if ctxLogger.LogLevel > TRACE {
+		return
+	}
+	ctxLogger.getPreparedSink().Write(SinkLevelTrace, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Debugln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.DEBUG {
 		return
 	}
-	ctxLogger.getPreparedLogger().Debugln(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelDebug, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Infoln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().Infoln(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelInfo, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Println(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().Println(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelInfo, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Warnln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().Warnln(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelWarn, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Warningln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().Warningln(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelWarn, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Errorln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	if ctxLogger.LogLevel > labstacklog.ERROR {
 		return
 	}
-	ctxLogger.getPreparedLogger().Errorln(addSpacesToArgs(args)...)
+	ctxLogger.getPreparedSink().Write(SinkLevelError, sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Fatalln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().Fatalln(addSpacesToArgs(args)...)
+	ctxLogger.fatal(ctxLogger.getPreparedSink(), sprintln(addSpacesToArgs(args)...))
 }
 func (ctxLogger *LoggerContextLogger) Panicln(args ...interface{}) {
 	ctxLogger.cache.Put(ctxLogger.LogLevel, args...)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().Panicln(addSpacesToArgs(args)...)
+	ctxLogger.writePanic(ctxLogger.getPreparedSink(), sprintln(addSpacesToArgs(args)...))
 }
 
+func (ctxLogger *LoggerContextLogger) Tracej(j labstacklog.JSON) {
+	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
+	if ctxLogger.LogLevel > TRACE {
+		return
+	}
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelTrace, `t`)
+}
 func (ctxLogger *LoggerContextLogger) Debugj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	if ctxLogger.LogLevel > labstacklog.DEBUG {
 		return
 	}
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Debug(`d`)
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelDebug, `d`)
 }
 func (ctxLogger *LoggerContextLogger) Infoj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Info(`i`)
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelInfo, `i`)
 }
 func (ctxLogger *LoggerContextLogger) Printj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	if ctxLogger.LogLevel > labstacklog.INFO {
 		return
 	}
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Print(`p`)
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelInfo, `p`)
 }
 func (ctxLogger *LoggerContextLogger) Warnj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Warn(`w`)
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelWarn, `w`)
 }
 func (ctxLogger *LoggerContextLogger) Warningj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	if ctxLogger.LogLevel > labstacklog.WARN {
 		return
 	}
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Warning(`w`)
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelWarn, `w`)
 }
 func (ctxLogger *LoggerContextLogger) Errorj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	if ctxLogger.LogLevel > labstacklog.ERROR {
 		return
 	}
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Error(`e`)
+	ctxLogger.getPreparedSink().WithFields(Fields(j)).Write(SinkLevelError, `e`)
 }
 func (ctxLogger *LoggerContextLogger) Fatalj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Fatal(`f`)
+	ctxLogger.fatal(ctxLogger.getPreparedSink().WithFields(Fields(j)), `f`)
 }
 func (ctxLogger *LoggerContextLogger) Panicj(j labstacklog.JSON) {
 	ctxLogger.cache.Putj(ctxLogger.LogLevel, j)
 	ctxLogger.IsStackTraceEnabled = true
-	ctxLogger.getPreparedLogger().WithFields(logrus.Fields(j)).Panic(`p`)
+	ctxLogger.writePanic(ctxLogger.getPreparedSink().WithFields(Fields(j)), `p`)
 }
 func (ctxLogger *LoggerContextLogger) SetOutput(w io.Writer) {
 	ctxLogger.ScopeEnableStackTrace(true).Warning(`Changing output of the logger`)
-	switch l := ctxLogger.logger.(type) {
-	case *logrus.Entry:
-		l.Logger.SetOutput(w)
-	case *logrus.Logger:
-		l.SetOutput(w)
-	default:
-		ctxLogger.Errorf(`Don't know how to set an output of logger of type "%T"`, l)
+	outputSetter, ok := ctxLogger.sink.(SinkOutputSetter)
+	if !ok {
+		ctxLogger.Errorf(`Don't know how to set an output of sink of type "%T"`, ctxLogger.sink)
+		return
 	}
+	outputSetter.SetOutput(w)
 }
 
 func (ctxLogger *LoggerContextLogger) Cache() []string {
@@ -460,9 +533,22 @@ func (ctx *LoggerContext) Release() {
 	if ctx.generator == nil {
 		return
 	}
+	if atomic.LoadUint64(&ctx.generation) != ctx.contextLogger.generation {
+		// Released twice, it should not get into the pool twice
+		ctx.contextLogger.checkReleased()
+		return
+	}
+	ctx.lineBudget.writeSummary(ctx.sink)
+	atomic.AddUint64(&ctx.generation, 1)
 	ctx.generator.releaseContext(ctx)
 }
 
+// writePanic writes the message at panic level and panics with it
+func (ctxLogger *LoggerContextLogger) writePanic(sink Sink, message string) {
+	sink.Write(SinkLevelPanic, message)
+	panic(message)
+}
+
 func addSpacesToArgs(args []interface{}) []interface{} {
 	if len(args) == 0 {
 		return args
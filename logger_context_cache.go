@@ -52,3 +52,15 @@ func (c *cache) Retrieve() []string {
 	copy(tmp, c.data)
 	return tmp
 }
+
+// Flush returns the cached logs and clears the cache
+func (c *cache) Flush() []string {
+	if c == nil {
+		return nil
+	}
+	c.Lock()
+	defer c.Unlock()
+	r := c.data
+	c.data = make([]string, 0, 0)
+	return r
+}
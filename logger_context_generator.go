@@ -21,9 +21,21 @@ type loggerContextGenerator struct {
 	enableStackTraceFraction float32
 	contextPool              sync.Pool // a pool of *loggerContext
 	requestIDGenPool         sync.Pool // a pool of *generateRequestIDReusables
-	defaultLogger            logrus.FieldLogger
+	sink                     Sink
 	defaultLogLevel          labstacklog.Lvl
 	cacheLogs                bool
+	headerRedactor           *headerRedactor
+	bodyFormatter            *bodyFormatter
+	accessLogger             *accessLogger
+	errorLogger              *errorLogger
+	recoverer                *recoverer
+	fatalPolicy              FatalPolicy
+	fatalExitHook            func()
+	slowRequestDetector      *slowRequestDetector
+	disableContextPooling    bool
+	auditWriter              *auditWriter
+	maxLinesPerRequest       int
+	maxBytesPerRequest       int
 }
 
 // The registry of all "loggerContextGenerator"'s.
@@ -34,38 +46,34 @@ type loggerContextGeneratorsT struct {
 
 var loggerContextGenerators = loggerContextGeneratorsT{}
 
-func fixLoggerLoggingLevel(logger logrus.FieldLogger) logrus.FieldLogger {
-	var entry *logrus.Entry
-
-	// TODO: remove this dirty hack
-	// Without this hack logrus filters logs according to his log levels
-	switch l := logger.(type) {
-	case *logrus.Entry:
-		entry = logrus.NewEntry(l.Logger).WithFields(l.Data)
-	case *logrus.Logger:
-		entry = logrus.NewEntry(l)
-	default:
-		logger.Error(`Unknown type: %T`, l)
-		return logger
-	}
-
-	entry.Level = logrus.TraceLevel // Log level filtering is done in the middleware. We should disable it in logrus, so we set "TraceLevel" (maximum logs)
-	return entry
-}
-
 func GetDefaultLogger() logrus.FieldLogger {
-	return fixLoggerLoggingLevel(logrus.StandardLogger())
+	return logrus.NewEntry(logrus.StandardLogger())
 }
 
+// newLoggerContextGenerator creates a generator and registers it, so
+// it's affected by SetDebugLogLevelFraction, SetDefaultLogLevel, etc.
 func newLoggerContextGenerator(opts Options) *loggerContextGenerator {
-	logger := opts.Logger
-	if logger == nil {
-		logger = GetDefaultLogger()
-	} else {
-		logger = fixLoggerLoggingLevel(logger)
+	gen := buildLoggerContextGenerator(opts)
+
+	loggerContextGenerators.Lock()
+	loggerContextGenerators.slice = append(loggerContextGenerators.slice, gen)
+	loggerContextGenerators.Unlock()
+
+	return gen
+}
+
+// buildLoggerContextGenerator creates a generator without registering it
+// (registered generators are never released)
+func buildLoggerContextGenerator(opts Options) *loggerContextGenerator {
+	sink := opts.Sink
+	if sink == nil {
+		sink = defaultLogrusSink
+		if opts.Logger != nil {
+			sink = NewLogrusSink(opts.Logger)
+		}
 	}
 
-	if opts.DefaultLogLevel == labstacklog.Lvl(0) {
+	if opts.DefaultLogLevel == labstacklog.Lvl(0) && !opts.IsDefaultLogLevelSet {
 		opts.DefaultLogLevel = defaultContextLoggerSettings.defaultLogLevel
 	}
 
@@ -91,13 +99,24 @@ func newLoggerContextGenerator(opts Options) *loggerContextGenerator {
 		debugLogLevelFraction:    opts.DebugLogLevelFraction,
 		enableStackTraceFraction: opts.EnableStackTraceFraction,
 		defaultLogLevel:          opts.DefaultLogLevel,
-		defaultLogger:            logger,
+		sink:                     sink,
 		cacheLogs:                opts.CacheLogs,
+		headerRedactor:           newHeaderRedactor(opts.HeaderRedaction, sink),
+		bodyFormatter:            newBodyFormatter(opts, newBodyRedactor(opts.BodyRedaction, sink)),
+		accessLogger:             newAccessLogger(opts.AccessLog, sink),
+		errorLogger:              newErrorLogger(opts.ErrorLog),
+		fatalPolicy:              opts.FatalPolicy,
+		fatalExitHook:            opts.FatalExitHook,
+		slowRequestDetector:      newSlowRequestDetector(opts),
+		disableContextPooling:    opts.DisableContextPooling,
+		auditWriter:              newAuditWriter(opts),
+		maxLinesPerRequest:       opts.MaxLinesPerRequest,
+		maxBytesPerRequest:       opts.MaxBytesPerRequest,
 	}
 
-	loggerContextGenerators.Lock()
-	loggerContextGenerators.slice = append(loggerContextGenerators.slice, gen)
-	loggerContextGenerators.Unlock()
+	if opts.Recover.Enable {
+		gen.recoverer = newRecoverer(opts.Recover)
+	}
 
 	return gen
 }
@@ -213,6 +232,8 @@ func TryParseLogLevel(s string, defaultLogLevel labstacklog.Lvl) labstacklog.Lvl
 		return defaultLogLevel
 	}
 	switch strings.ToLower(s) {
+	case `t`, `trace`:
+		return TRACE
 	case `d`, `debug`:
 		return labstacklog.DEBUG
 	case `i`, `info`:
@@ -279,7 +300,7 @@ func (h *loggerContextGenerator) AcquireContext(c echo.Context) *LoggerContext {
 	newContext.init(
 		h, c,
 		h.getRequestID(c),
-		h.defaultLogger,
+		h.sink,
 		logLevel,
 		isStackTraceEnabled,
 		h.cacheLogs,
@@ -290,5 +311,10 @@ func (h *loggerContextGenerator) AcquireContext(c echo.Context) *LoggerContext {
 }
 
 func (h *loggerContextGenerator) releaseContext(ctx echo.Context) {
+	// Released contexts are not reused if use-after-release is checked, otherwise
+	// a stale reference to a reused context is not distinguishable from a valid one
+	if h.disableContextPooling || useAfterReleaseMode != useAfterReleaseIgnore {
+		return
+	}
 	h.contextPool.Put(ctx)
 }
@@ -1,38 +1,25 @@
 package echolog
 
 import (
-	"bytes"
-
 	labstacklog "github.com/labstack/gommon/log"
-	"github.com/sirupsen/logrus"
 	"github.com/trafficstars/echo"
-	"github.com/trafficstars/echo/engine"
-	"github.com/trafficstars/echo/engine/fasthttp"
 )
 
-// getHeaders extracts HTTP headers from an engine.Request
-func getHeaders(headerObj engine.Header) map[string]string {
-	headers := map[string]string{}
-	for _, k := range headerObj.Keys() {
-		headers[k] = headerObj.Get(k)
-	}
-
-	return headers
-}
-
-// getBodyAndHeadersFromRequest extracts HTTP request body and headers from an engine.Request
-func getBodyAndHeadersFromRequest(req engine.Request) (body string, headers map[string]string) {
-	var bodyBuf bytes.Buffer
-	bodyBuf.ReadFrom(req.Body())
-
-	return bodyBuf.String(), getHeaders(req.Header())
-}
-
 // Middleware is the function to be used as an argument to method `Use()` of an echo router
 //
 // This's the function that should be used from external packages.
 func Middleware(opts Options) echo.MiddlewareFunc {
 	loggerContextGenerator := newLoggerContextGenerator(opts)
+	maxRequestBodySize := normalizeMaxBodySize(opts.MaxRequestBodySize)
+	maxResponseBodySize := normalizeMaxBodySize(opts.MaxResponseBodySize)
+
+	// The exchange could be logged regardless of the log level of the request
+	// (by the slow request detector, the panic dump, etc), so it should be
+	// recorded for every request
+	isExchangeRecordingForced := loggerContextGenerator.slowRequestDetector != nil ||
+		(loggerContextGenerator.recoverer != nil && !loggerContextGenerator.recoverer.disableExchangeDump) ||
+		opts.LogCurl ||
+		opts.HARWriter != nil
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(echoContext echo.Context) (err error) {
@@ -40,6 +27,24 @@ func Middleware(opts Options) echo.MiddlewareFunc {
 			// Get a context with an embedded logger
 			c := loggerContextGenerator.AcquireContext(echoContext)
 
+			// Make the logger available to code which takes context.Context (see FromContext)
+			c.storeInStdContext()
+
+			// Record the exchange only if it could be logged. If the log level
+			// is lowered to DEBUG by the handler then the bodies are not logged.
+			isExchangeRecorded := !opts.Disable && (isExchangeRecordingForced || c.LogLevel <= labstacklog.DEBUG)
+
+			if isExchangeRecorded {
+				// Start capturing the request body before the handler consumes it
+				// and the response body before the handler writes it
+				c.requestBodyRecorder = newRequestBodyRecorder(c.Request(), maxRequestBodySize, opts.HashTruncatedBodies)
+				c.responseBodyRecorder = newResponseBodyRecorder(c.Response(), maxResponseBodySize, opts.HashTruncatedBodies)
+			}
+
+			if !opts.DisableServerTiming {
+				c.installServerTiming()
+			}
+
 			defer func() {
 				c.Response().Header().Set(`X-Request-Id`, c.GetRequestID())
 				// Release the context to reuse it in future
@@ -49,7 +54,29 @@ func Middleware(opts Options) echo.MiddlewareFunc {
 
 			// OK, now we call the real request handler
 			// This handler can call logger's methods from the context
-			err = next(c)
+			stopWatchdog := loggerContextGenerator.slowRequestDetector.startWatchdog(c)
+			// Deferred, so the watchdog is stopped even if the handler panics
+			defer stopWatchdog()
+			if loggerContextGenerator.recoverer != nil {
+				err = loggerContextGenerator.recoverer.call(next, c)
+			} else {
+				err = next(c)
+			}
+
+			if !opts.DisableServerTiming {
+				c.finishServerTiming()
+			}
+
+			loggerContextGenerator.errorLogger.write(c, err)
+
+			if !opts.AccessLog.Disable {
+				loggerContextGenerator.accessLogger.write(c, newAccessLogEntry(c, err))
+			}
+
+			if loggerContextGenerator.slowRequestDetector.checkFinished(c) {
+				// Flush the request / response of slow requests regardless of the log level
+				c.isExchangeLogForced = true
+			}
 
 			if opts.Disable {
 				return
@@ -69,34 +96,63 @@ func Middleware(opts Options) echo.MiddlewareFunc {
 					defer func() { c.LogLevel = currentLogLevel }()
 				}
 
-				// Retrieve response body from fasthttp response object
-				var responseBody string
-				val, ok := c.Response().(*fasthttp.Response)
-				if ok {
-					responseBody = string(val.RequestCtx.Response.Body())
+				// Collect request and response bodies and headers
+				requestHeader := echoContext.Request().Header()
+				responseHeader := c.responseBodyRecorder.header(c.Response())
+				requestBody := c.requestBodyRecorder.body()
+				responseBody := c.responseBodyRecorder.body()
+
+				// Decode bodies according to their content types and mask sensitive data
+				bodyFormatter := loggerContextGenerator.bodyFormatter
+				requestBodyString, requestBodyFields := bodyFormatter.format(requestBody, requestHeader.Get(`Content-Type`), requestHeader.Get(`Content-Encoding`), maxRequestBodySize)
+				responseBodyString, responseBodyFields := bodyFormatter.format(responseBody, responseHeader.Get(`Content-Type`), responseHeader.Get(`Content-Encoding`), maxResponseBodySize)
+
+				requestHeaders := getHeaders(requestHeader, loggerContextGenerator.headerRedactor)
+				responseHeaders := getHeaders(responseHeader, loggerContextGenerator.headerRedactor)
+				responseStatus := c.responseBodyRecorder.status(c.Response())
+
+				if opts.LogCurl {
+					if requestBodyFields == nil {
+						requestBodyFields = Fields{}
+					}
+					requestBodyFields[`curl`] = c.AsCurl()
 				}
 
-				// Collect request body and headers
-				body, headers := getBodyAndHeadersFromRequest(echoContext.Request())
-
 				// Log request
-				c.WithFields(logrus.Fields{
+				c.WithFields(Fields{
 					`what`:         `http_request`,
 					`method`:       echoContext.Request().Method(),
 					`url`:          echoContext.Request().URL().Path(),
 					`query_params`: echoContext.Request().URL().QueryString(),
-					`http_headers`: headers,
-				}).Debug(body)
+					`http_headers`: requestHeaders,
+				}).WithFields(requestBodyFields).Debug(requestBodyString)
 
 				// Log Response
-				c.WithFields(logrus.Fields{
+				c.WithFields(Fields{
 					`what`:         `http_response`,
 					`method`:       echoContext.Request().Method(),
 					`url`:          echoContext.Request().URL().Path(),
 					`query_params`: echoContext.Request().URL().QueryString(),
-					`http_headers`: getHeaders(echoContext.Response().Header()),
-					`http_code`:    echoContext.Response().Status(),
-				}).Debug(responseBody)
+					`http_headers`: responseHeaders,
+					`http_code`:    responseStatus,
+				}).WithFields(responseBodyFields).Debug(responseBodyString)
+
+				if opts.HARWriter != nil {
+					err := opts.HARWriter.Add(newHAREntry(harExchange{
+						c:                  c,
+						requestHeaders:     requestHeaders,
+						requestBody:        requestBody,
+						requestBodyString:  requestBodyString,
+						responseHeaders:    responseHeaders,
+						responseStatus:     responseStatus,
+						responseBody:       responseBody,
+						responseBodyString: responseBodyString,
+						isResponseBase64:   responseBodyFields[`body_base64`] == true,
+					}))
+					if err != nil {
+						c.Errorf(`Unable to write a HAR entry: %v`, err)
+					}
+				}
 			})
 
 			return
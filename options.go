@@ -1,15 +1,47 @@
 package echolog
 
 import (
+	"io"
+	"time"
+
 	labstacklog "github.com/labstack/gommon/log"
 	"github.com/sirupsen/logrus"
 )
 
 type Options struct {
-	Disable                  bool    // Disable request / response logging
+	Disable                  bool    // Disable request / response logging (the access log and the error log are disabled with AccessLog.Disable and ErrorLog.Disable)
 	CacheLogs                bool    // Save session logs in buffer, can be retrieved with .Cache()
 	DebugLogLevelFraction    float32 // A fraction of traffic that should be logged on all levels
 	EnableStackTraceFraction float32 // A fraction of requests, which will be logged with attached stack traces.
 	DefaultLogLevel          labstacklog.Lvl
-	Logger                   logrus.FieldLogger
+	IsDefaultLogLevelSet     bool                   // Use DefaultLogLevel even if it's zero (TRACE), otherwise zero means the default level (see SetDefaultLogLevel)
+	Logger                   logrus.FieldLogger     // Where to write logs (the standard logrus logger by default), it's ignored if Sink is set
+	Sink                     Sink                   // Where to write logs through another backend, see NewSlogSink and the subpackages zapsink and zerologsink
+	HeaderRedaction          HeaderRedactionOptions // Redaction of sensitive headers (Authorization, Cookie, etc) in request / response logs
+	BodyRedaction            BodyRedactionOptions   // Redaction of sensitive data (passwords, card numbers, etc) in request / response bodies
+	MaxRequestBodySize       int                    // The maximal amount of bytes of a request body to be logged (DefaultMaxBodySize if zero, no limit if negative)
+	MaxResponseBodySize      int                    // The maximal amount of bytes of a response body to be logged (DefaultMaxBodySize if zero, no limit if negative)
+	HashTruncatedBodies      bool                   // Log a SHA-256 hash of the full body if it was truncated
+	BinaryBodyMode           BinaryBodyMode         // How to log bodies of binary content types (skipped by default)
+	JSONBodyFormat           JSONBodyFormat         // How to format JSON bodies (as is by default)
+	AccessLog                AccessLogOptions       // A single line per request written at the end of the request
+	HARWriter                *HARWriter             // If set then each logged request / response is also collected as a HAR entry
+	LogCurl                  bool                   // Add a field "curl" with a command reproducing the request to request logs
+	ErrorLog                 ErrorLogOptions        // Logging of errors returned by handlers
+	Recover                  RecoverOptions         // Recovering of panics in handlers
+	FatalPolicy              FatalPolicy            // What Fatal* methods of the logger do (exit the process by default)
+	FatalExitHook            func()                 // Is called before the exit by FatalPolicyExit, for example to flush asynchronous writers
+	DisableServerTiming      bool                   // Do not send spans and marks of the request (see LoggerContext.StartSpan) in the "Server-Timing" header
+	DisableContextPooling    bool                   // Do not reuse released LoggerContext-s (useful to chase use-after-release bugs, see also the "echolog_debug" build tag)
+	MaxLinesPerRequest       int                    // Further log lines of a request are dropped after this amount of lines (no limit if zero), a summary is written on Release()
+	MaxBytesPerRequest       int                    // Further log lines of a request are dropped after this amount of bytes of messages (no limit if zero), a summary is written on Release()
+
+	SlowRequestThreshold       time.Duration            // Requests longer than this are logged with a "slow_request" warning (with their request / response and cached logs)
+	SlowRequestRouteThresholds map[string]time.Duration // Per route thresholds, the key is a route ("/users/:id") optionally prefixed by a method ("GET /users/:id")
+	InFlightWarningThreshold   time.Duration            // Requests which are still running after this are logged with a warning (with the stack of the handler)
+
+	AuditWriter    io.Writer                     // Where to write audit events (see LoggerContext.Audit), one JSON per line
+	AuditActorFunc func(c *LoggerContext) string // Returns the actor of an audit event (the user of the basic authorization by default)
+	AuditHashChain bool                          // Chain audit events by hashes to detect tampering (see VerifyAuditLog)
+	AuditPrevHash  string                        // The hash of the last event already written to AuditWriter, to continue the chain after a restart (see AuditLogLastHash)
 }
@@ -0,0 +1,172 @@
+package echolog
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/trafficstars/echo"
+)
+
+const recoverMaxStackDepth = 64
+
+// RecoverOptions configures recovering of panics in handlers
+type RecoverOptions struct {
+	Enable              bool // Recover panics in Middleware (not required if the Recover middleware is used)
+	DisableExchangeDump bool // Do not attach the captured request to the log entry
+	DisableCachedLogs   bool // Do not attach the cached logs of the request (see Options.CacheLogs) to the log entry
+}
+
+// PanicError is returned by a handler wrapped with the panic recovery
+// instead of the panic. The echo's HTTP error handler responds with
+// "500 Internal Server Error" on it.
+type PanicError struct {
+	Value interface{}
+	Stack []StackFrame
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf(`panic: %v`, err.Value)
+}
+
+// StackFrame is a frame of a stack trace
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// getPanicStack returns the stack of the panicking goroutine, it should be
+// called from the deferred function which recovers the panic
+func getPanicStack() []StackFrame {
+	pcs := make([]uintptr, recoverMaxStackDepth)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []StackFrame
+	isPanicFound := false
+	for {
+		frame, more := frames.Next()
+		if isPanicFound {
+			stack = append(stack, StackFrame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+			})
+		} else if strings.HasPrefix(frame.Function, `runtime.gopanic`) {
+			// Skip the frames of the recovery itself
+			isPanicFound = true
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+type recoverer struct {
+	disableExchangeDump bool
+	disableCachedLogs   bool
+}
+
+func newRecoverer(opts RecoverOptions) *recoverer {
+	return &recoverer{
+		disableExchangeDump: opts.DisableExchangeDump,
+		disableCachedLogs:   opts.DisableCachedLogs,
+	}
+}
+
+// Recover is a middleware which recovers panics in handlers and logs them
+// through the logger of the request. It should be used after Middleware
+// (otherwise panics are logged through GetDefaultContextLogger()).
+//
+// The panic is converted to *PanicError, so the response is written
+// by the echo's HTTP error handler.
+//
+// The request is dumped only if it's recorded by Middleware, which is done
+// for requests with DEBUG level (or if Options.Recover is enabled).
+func Recover(opts RecoverOptions) echo.MiddlewareFunc {
+	r := newRecoverer(opts)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return r.call(next, c)
+		}
+	}
+}
+
+// call calls the handler and converts its panic to *PanicError
+func (r *recoverer) call(next echo.HandlerFunc, c echo.Context) (err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		panicErr := &PanicError{
+			Value: p,
+			Stack: getPanicStack(),
+		}
+		r.log(c, panicErr)
+		err = panicErr
+	}()
+	return next(c)
+}
+
+func (r *recoverer) log(c echo.Context, panicErr *PanicError) {
+	fields := Fields{
+		`what`:   `panic`,
+		`panic`:  fmt.Sprint(panicErr.Value),
+		`stack`:  panicErr.Stack,
+		`method`: c.Request().Method(),
+		`route`:  c.Path(),
+		`url`:    c.Request().URL().Path(),
+	}
+
+	loggerContext, ok := c.(*LoggerContext)
+	if !ok {
+		GetDefaultContextLogger().sink.WithFields(fields).Write(SinkLevelError, panicErr.Error())
+		return
+	}
+
+	if !r.disableExchangeDump && loggerContext.requestBodyRecorder != nil {
+		fields[`exchange`] = loggerContext.dumpRequest()
+	}
+	if !r.disableCachedLogs && loggerContext.cache != nil {
+		fields[`cached_logs`] = loggerContext.Cache()
+	}
+
+	// Panics are logged regardless of the log level of the request
+	loggerContext.sink.WithFields(fields).Write(SinkLevelError, panicErr.Error())
+}
+
+// dumpRequest returns the captured request (with sensitive data redacted)
+func (ctx *LoggerContext) dumpRequest() Fields {
+	req := ctx.Request()
+	body := ctx.requestBodyRecorder.body()
+
+	var (
+		headerRedactor *headerRedactor
+		bodyString     string
+		bodyFields     Fields
+	)
+	if ctx.generator != nil {
+		headerRedactor = ctx.generator.headerRedactor
+		bodyString, bodyFields = ctx.generator.bodyFormatter.format(
+			body,
+			req.Header().Get(`Content-Type`),
+			req.Header().Get(`Content-Encoding`),
+			ctx.requestBodyRecorder.capturer.limit,
+		)
+	}
+
+	dump := Fields{
+		`method`:       req.Method(),
+		`url`:          req.URL().Path(),
+		`query_params`: req.URL().QueryString(),
+		`http_headers`: getHeaders(req.Header(), headerRedactor),
+		`body`:         bodyString,
+	}
+	for k, v := range bodyFields {
+		dump[k] = v
+	}
+	return dump
+}
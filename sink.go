@@ -0,0 +1,90 @@
+package echolog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Sink is a logging backend. The filtering by the log level of the request,
+// caching, stack traces, etc are done by LoggerContextLogger, so a Sink just
+// writes final messages.
+//
+// There're adapters for logrus (NewLogrusSink) and log/slog (NewSlogSink).
+// Adapters for zap and zerolog are in the subpackages zapsink and zerologsink
+// (they're separate modules, so the core doesn't depend on them).
+//
+// All the adapters write through the backend, so messages are filtered by
+// the level of the backend as well. The backend should be set to its lowest
+// level to let the log level of the request decide alone (for example for
+// requests with DebugLogLevelFraction or "x_log_level=debug"): logrus.TraceLevel,
+// SlogLevelTrace, zapcore.DebugLevel or zerolog.TraceLevel (both the logger
+// and the global level).
+type Sink interface {
+	// WithFields returns a Sink which adds the fields to each message
+	WithFields(fields Fields) Sink
+
+	// Write writes the message. It shouldn't exit or panic on
+	// SinkLevelFatal and SinkLevelPanic, it's done by the caller.
+	Write(level SinkLevel, message string)
+}
+
+// SinkOutputSetter is an optional interface of a Sink, see LoggerContextLogger.SetOutput
+type SinkOutputSetter interface {
+	SetOutput(w io.Writer)
+}
+
+// SinkExiter is an optional interface of a Sink to exit the process after
+// a fatal message (for example to flush buffers), os.Exit(1) is used otherwise
+type SinkExiter interface {
+	Exit(code int)
+}
+
+// SinkLevel is a level of a message passed to a Sink
+type SinkLevel int
+
+const (
+	SinkLevelTrace SinkLevel = iota
+	SinkLevelDebug
+	SinkLevelInfo
+	SinkLevelWarn
+	SinkLevelError
+	SinkLevelFatal
+	SinkLevelPanic
+)
+
+func (level SinkLevel) String() string {
+	switch level {
+	case SinkLevelTrace:
+		return `trace`
+	case SinkLevelDebug:
+		return `debug`
+	case SinkLevelInfo:
+		return `info`
+	case SinkLevelWarn:
+		return `warning`
+	case SinkLevelError:
+		return `error`
+	case SinkLevelFatal:
+		return `fatal`
+	case SinkLevelPanic:
+		return `panic`
+	}
+	return fmt.Sprintf(`level(%d)`, int(level))
+}
+
+// sortedFieldKeys returns the keys of the fields in a stable order (for
+// backends which keep the order of fields)
+func sortedFieldKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeSinkf is a shortcut to write a formatted message to a Sink
+func writeSinkf(sink Sink, level SinkLevel, format string, args ...interface{}) {
+	sink.Write(level, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,65 @@
+package echolog
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultLogrusSink writes to the standard logger of logrus. It's shared,
+// so GetDefaultContextLogger() doesn't create a sink on each call.
+var defaultLogrusSink = NewLogrusSink(GetDefaultLogger())
+
+type logrusSink struct {
+	entry *logrus.Entry
+}
+
+// NewLogrusSink returns a Sink writing to a logrus logger.
+//
+// Messages are written through the logger itself, so they're filtered by the
+// log level of the request (see Options.DefaultLogLevel) and then by the level
+// of the logger. Set the level of the logger to logrus.TraceLevel to let the
+// log level of the request decide alone (for example for requests with
+// DebugLogLevelFraction). Hooks and formatters see the real level of each message.
+func NewLogrusSink(logger logrus.FieldLogger) Sink {
+	return logrusSink{entry: logger.WithFields(logrus.Fields{})}
+}
+
+func getLogrusLevel(level SinkLevel) logrus.Level {
+	switch level {
+	case SinkLevelTrace:
+		return logrus.TraceLevel
+	case SinkLevelDebug:
+		return logrus.DebugLevel
+	case SinkLevelInfo:
+		return logrus.InfoLevel
+	case SinkLevelWarn:
+		return logrus.WarnLevel
+	case SinkLevelFatal:
+		return logrus.FatalLevel
+	case SinkLevelPanic:
+		return logrus.PanicLevel
+	}
+	return logrus.ErrorLevel
+}
+
+func (s logrusSink) WithFields(fields Fields) Sink {
+	return logrusSink{entry: s.entry.WithFields(fields)}
+}
+
+func (s logrusSink) Write(level SinkLevel, message string) {
+	if level == SinkLevelPanic {
+		// logrus panics after writing a message at PanicLevel, but it's up to the caller
+		defer func() { _ = recover() }()
+	}
+
+	s.entry.Log(getLogrusLevel(level), message)
+}
+
+func (s logrusSink) SetOutput(w io.Writer) {
+	s.entry.Logger.SetOutput(w)
+}
+
+func (s logrusSink) Exit(code int) {
+	s.entry.Logger.Exit(code)
+}
@@ -0,0 +1,81 @@
+package echolog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type levelRecordingHook struct {
+	levels []logrus.Level
+}
+
+func (h *levelRecordingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *levelRecordingHook) Fire(entry *logrus.Entry) error {
+	// It's called under the lock of the logger, so it's not synchronized here
+	h.levels = append(h.levels, entry.Level)
+	return nil
+}
+
+// TestLogrusSinkConcurrentWrites should be run with -race: writes (and hooks)
+// should be synchronized by the lock of the logger
+func TestLogrusSinkConcurrentWrites(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetLevel(logrus.TraceLevel)
+	logger.SetOutput(&buf)
+	hook := &levelRecordingHook{}
+	logger.AddHook(hook)
+
+	const goroutines, messages = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sink := NewLogrusSink(logger).WithFields(Fields{`a`: 1})
+			for j := 0; j < messages; j++ {
+				sink.Write(SinkLevelDebug, `message`)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logger.SetOutput(&buf)
+		logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	}()
+	wg.Wait()
+
+	if lines := strings.Count(buf.String(), "\n"); lines != goroutines*messages {
+		t.Errorf(`%d lines are written; expected %d`, lines, goroutines*messages)
+	}
+	if len(hook.levels) != goroutines*messages {
+		t.Fatalf(`the hook is fired %d times; expected %d`, len(hook.levels), goroutines*messages)
+	}
+	if hook.levels[0] != logrus.DebugLevel {
+		t.Errorf(`the hook got level %v; expected %v`, hook.levels[0], logrus.DebugLevel)
+	}
+}
+
+func TestLogrusSinkLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+
+	sink := NewLogrusSink(logger.WithField(`a`, 1))
+	sink.Write(SinkLevelDebug, `debug`)
+	sink.Write(SinkLevelWarn, `warning`)
+
+	if expected := "level=warning msg=warning a=1\n"; buf.String() != expected {
+		t.Errorf(`written %q; expected %q`, buf.String(), expected)
+	}
+}
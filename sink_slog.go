@@ -0,0 +1,53 @@
+package echolog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Levels used by the slog Sink for trace, fatal and panic messages (slog has no such levels)
+const (
+	SlogLevelTrace = slog.LevelDebug - 4
+	SlogLevelFatal = slog.LevelError + 4
+	SlogLevelPanic = slog.LevelError + 8
+)
+
+type slogSink struct {
+	logger *slog.Logger
+}
+
+// NewSlogSink returns a Sink writing to a log/slog logger. Messages are
+// filtered by the handler of the logger as well (see Sink).
+func NewSlogSink(logger *slog.Logger) Sink {
+	return slogSink{logger: logger}
+}
+
+func getSlogLevel(level SinkLevel) slog.Level {
+	switch level {
+	case SinkLevelTrace:
+		return SlogLevelTrace
+	case SinkLevelDebug:
+		return slog.LevelDebug
+	case SinkLevelInfo:
+		return slog.LevelInfo
+	case SinkLevelWarn:
+		return slog.LevelWarn
+	case SinkLevelFatal:
+		return SlogLevelFatal
+	case SinkLevelPanic:
+		return SlogLevelPanic
+	}
+	return slog.LevelError
+}
+
+func (s slogSink) WithFields(fields Fields) Sink {
+	args := make([]interface{}, 0, len(fields))
+	for _, key := range sortedFieldKeys(fields) {
+		args = append(args, slog.Any(key, fields[key]))
+	}
+	return slogSink{logger: s.logger.With(args...)}
+}
+
+func (s slogSink) Write(level SinkLevel, message string) {
+	s.logger.Log(context.Background(), getSlogLevel(level), message)
+}
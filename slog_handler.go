@@ -0,0 +1,152 @@
+package echolog
+
+import (
+	"context"
+	"log/slog"
+
+	labstacklog "github.com/labstack/gommon/log"
+)
+
+// slogHandler is a slog.Handler writing to a LoggerContextLogger, so the
+// messages get the request ID, are filtered by the log level of the
+// request, are cached, etc.
+type slogHandler struct {
+	ctxLogger *LoggerContextLogger
+	fields    Fields   // Attributes added by WithAttrs (groups are nested fields)
+	groups    []string // Groups opened by WithGroup
+}
+
+// NewSlogHandler returns a slog.Handler writing to the logger, attribute
+// groups are written as nested fields
+func NewSlogHandler(ctxLogger *LoggerContextLogger) slog.Handler {
+	return &slogHandler{
+		ctxLogger: ctxLogger,
+		fields:    Fields{},
+	}
+}
+
+// Slog returns a *slog.Logger writing to the logger (see NewSlogHandler).
+// It's useful to pass the logger of the request to libraries accepting *slog.Logger.
+func (ctxLogger *LoggerContextLogger) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(ctxLogger))
+}
+
+func getLogLevelBySlogLevel(level slog.Level) labstacklog.Lvl {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE
+	case level < slog.LevelInfo:
+		return labstacklog.DEBUG
+	case level < slog.LevelWarn:
+		return labstacklog.INFO
+	case level < slog.LevelError:
+		return labstacklog.WARN
+	}
+	return labstacklog.ERROR
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.ctxLogger.LogLevel <= getLogLevelBySlogLevel(level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := copyFields(h.fields)
+	group := getFieldsGroup(fields, h.groups)
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(group, attr)
+		return true
+	})
+
+	logger := h.ctxLogger.WithFields(fields)
+	switch getLogLevelBySlogLevel(record.Level) {
+	case TRACE:
+		logger.Trace(record.Message)
+	case labstacklog.DEBUG:
+		logger.Debug(record.Message)
+	case labstacklog.INFO:
+		logger.Info(record.Message)
+	case labstacklog.WARN:
+		logger.Warn(record.Message)
+	default:
+		logger.Error(record.Message)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := copyFields(h.fields)
+	group := getFieldsGroup(fields, h.groups)
+	for _, attr := range attrs {
+		addSlogAttr(group, attr)
+	}
+	return &slogHandler{
+		ctxLogger: h.ctxLogger,
+		fields:    fields,
+		groups:    h.groups,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == `` {
+		return h
+	}
+	groups := make([]string, len(h.groups), len(h.groups)+1)
+	copy(groups, h.groups)
+	return &slogHandler{
+		ctxLogger: h.ctxLogger,
+		fields:    h.fields,
+		groups:    append(groups, name),
+	}
+}
+
+// copyFields returns a deep copy of fields (nested groups are copied as well)
+func copyFields(fields Fields) Fields {
+	r := make(Fields, len(fields))
+	for k, v := range fields {
+		if group, ok := v.(Fields); ok {
+			v = copyFields(group)
+		}
+		r[k] = v
+	}
+	return r
+}
+
+// getFieldsGroup returns the nested fields of the group path (creating them if required)
+func getFieldsGroup(fields Fields, groups []string) Fields {
+	for _, name := range groups {
+		group, ok := fields[name].(Fields)
+		if !ok {
+			group = Fields{}
+			fields[name] = group
+		}
+		fields = group
+	}
+	return fields
+}
+
+// addSlogAttr adds the attribute to fields, groups are added as nested fields
+func addSlogAttr(fields Fields, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		fields[attr.Key] = attr.Value.Any()
+		return
+	}
+
+	groupAttrs := attr.Value.Group()
+	if len(groupAttrs) == 0 {
+		return
+	}
+	group := fields
+	if attr.Key != `` {
+		group = getFieldsGroup(fields, []string{attr.Key})
+	}
+	for _, groupAttr := range groupAttrs {
+		addSlogAttr(group, groupAttr)
+	}
+}
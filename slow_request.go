@@ -0,0 +1,135 @@
+package echolog
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+const (
+	goroutineStacksInitialBufferSize = 64 * 1024
+	goroutineStacksMaxBufferSize     = 16 * 1024 * 1024
+)
+
+type slowRequestDetector struct {
+	threshold         time.Duration
+	routeThresholds   map[string]time.Duration
+	inFlightThreshold time.Duration
+}
+
+func newSlowRequestDetector(opts Options) *slowRequestDetector {
+	if opts.SlowRequestThreshold <= 0 && len(opts.SlowRequestRouteThresholds) == 0 && opts.InFlightWarningThreshold <= 0 {
+		return nil
+	}
+	return &slowRequestDetector{
+		threshold:         opts.SlowRequestThreshold,
+		routeThresholds:   opts.SlowRequestRouteThresholds,
+		inFlightThreshold: opts.InFlightWarningThreshold,
+	}
+}
+
+// getThreshold returns the threshold for the route, routes could be
+// defined with a method ("GET /users/:id") or without it ("/users/:id")
+func (d *slowRequestDetector) getThreshold(method, route string) time.Duration {
+	if threshold, ok := d.routeThresholds[method+` `+route]; ok {
+		return threshold
+	}
+	if threshold, ok := d.routeThresholds[route]; ok {
+		return threshold
+	}
+	return d.threshold
+}
+
+// checkFinished reports the request if it was too slow. It returns true if the request is slow.
+func (d *slowRequestDetector) checkFinished(c *LoggerContext) bool {
+	if d == nil {
+		return false
+	}
+	threshold := d.getThreshold(c.Request().Method(), c.Path())
+	latency := time.Since(c.StartTime)
+	if threshold <= 0 || latency <= threshold {
+		return false
+	}
+
+	fields := Fields{
+		`what`:         `slow_request`,
+		`slow_request`: true,
+		`method`:       c.Request().Method(),
+		`route`:        c.Path(),
+		`url`:          c.Request().URL().Path(),
+		`latency`:      latency,
+		`threshold`:    threshold,
+	}
+
+	// The logs of the request are flushed regardless of the log level of the request
+	if c.cache != nil {
+		fields[`cached_logs`] = c.Cache()
+	}
+	writeSinkf(c.sink.WithFields(fields), SinkLevelWarn, `slow request: %v (threshold: %v)`, latency, threshold)
+	return true
+}
+
+// startWatchdog starts a timer which reports the request if it's still
+// in flight after InFlightWarningThreshold. The returned function should
+// be called when the request is finished.
+func (d *slowRequestDetector) startWatchdog(c *LoggerContext) (stop func()) {
+	if d == nil || d.inFlightThreshold <= 0 {
+		return func() {}
+	}
+
+	// The context could be reused by another request while the timer is
+	// firing, so everything required is copied here
+	sink := c.sink
+	startTime := c.StartTime
+	fields := Fields{
+		`what`:   `in_flight_request`,
+		`method`: c.Request().Method(),
+		`route`:  c.Path(),
+		`url`:    c.Request().URL().Path(),
+	}
+	goroutineID := getGoroutineID()
+
+	timer := time.AfterFunc(d.inFlightThreshold, func() {
+		inFlight := time.Since(startTime)
+		writeSinkf(sink.WithFields(fields).WithFields(Fields{
+			`in_flight`:       inFlight,
+			`goroutine_stack`: getGoroutineStack(goroutineID),
+		}), SinkLevelWarn, `request is in flight for %v`, inFlight.Truncate(time.Millisecond))
+	})
+	return func() { timer.Stop() }
+}
+
+// getGoroutineID returns the ID of the current goroutine (parsed from the stack trace header)
+func getGoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte(`goroutine `))
+	if idx := bytes.IndexByte(b, ' '); idx != -1 {
+		b = b[:idx]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// getGoroutineStack returns the stack trace of the goroutine by its ID
+func getGoroutineStack(goroutineID uint64) string {
+	var stacks []byte
+	for size := goroutineStacksInitialBufferSize; ; size *= 2 {
+		buf := make([]byte, size)
+		n := runtime.Stack(buf, true)
+		if n < size || size >= goroutineStacksMaxBufferSize {
+			stacks = buf[:n]
+			break
+		}
+	}
+
+	header := []byte(fmt.Sprintf("goroutine %d [", goroutineID))
+	for _, stack := range bytes.Split(stacks, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, header) {
+			return string(stack)
+		}
+	}
+	return ``
+}
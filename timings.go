@@ -0,0 +1,181 @@
+package echolog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trafficstars/echo/engine/fasthttp"
+	"github.com/trafficstars/echo/engine/standard"
+)
+
+// Timing is a named duration within a request (see LoggerContext.StartSpan and LoggerContext.Mark)
+type Timing struct {
+	Name     string        `json:"name"`
+	Start    time.Duration `json:"start"`    // Since the start of the request
+	Duration time.Duration `json:"duration"` // Zero for marks
+	IsMark   bool          `json:"is_mark,omitempty"`
+}
+
+type timings struct {
+	sync.Mutex
+	startTime time.Time
+	data      []Timing
+}
+
+func (t *timings) reset(startTime time.Time) {
+	t.Lock()
+	t.startTime = startTime
+	t.data = t.data[:0]
+	t.Unlock()
+}
+
+func (t *timings) add(timing Timing) {
+	t.Lock()
+	t.data = append(t.data, timing)
+	t.Unlock()
+}
+
+func (t *timings) get() []Timing {
+	t.Lock()
+	defer t.Unlock()
+	if len(t.data) == 0 {
+		return nil
+	}
+	r := make([]Timing, len(t.data))
+	copy(r, t.data)
+	return r
+}
+
+// Span measures a phase of a request, see LoggerContext.StartSpan
+type Span struct {
+	timings *timings
+	name    string
+	start   time.Time
+	once    sync.Once
+}
+
+// StartSpan starts measuring a named phase of the request (for example "db.query").
+// The phase ends when End() is called. The timings are attached to the access log
+// and sent in the "Server-Timing" response header.
+func (ctx *LoggerContext) StartSpan(name string) *Span {
+	return &Span{
+		timings: &ctx.timings,
+		name:    name,
+		start:   time.Now(),
+	}
+}
+
+// End finishes the span, only the first call has an effect
+func (span *Span) End() {
+	span.once.Do(func() {
+		span.timings.add(Timing{
+			Name:     span.name,
+			Start:    span.start.Sub(span.timings.startTime),
+			Duration: time.Since(span.start),
+		})
+	})
+}
+
+// Mark records a named point in time of the request (for example "cache_miss")
+func (ctx *LoggerContext) Mark(name string) {
+	ctx.timings.add(Timing{
+		Name:   name,
+		Start:  time.Since(ctx.StartTime),
+		IsMark: true,
+	})
+}
+
+// Timings returns all finished spans and marks of the request
+func (ctx *LoggerContext) Timings() []Timing {
+	return ctx.timings.get()
+}
+
+// serverTimingMetricName converts a name to a valid token of the Server-Timing header
+func serverTimingMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// formatServerTiming returns the value of the "Server-Timing" header,
+// marks are sent with the time since the start of the request as the duration
+func formatServerTiming(timings []Timing) string {
+	var buf strings.Builder
+	for idx, timing := range timings {
+		if idx > 0 {
+			buf.WriteString(`, `)
+		}
+		duration := timing.Duration
+		if timing.IsMark {
+			duration = timing.Start
+		}
+		buf.WriteString(serverTimingMetricName(timing.Name))
+		buf.WriteString(`;dur=`)
+		buf.WriteString(strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', 3, 64))
+	}
+	return buf.String()
+}
+
+// setServerTimingHeader sets the "Server-Timing" header if there're timings
+func (ctx *LoggerContext) setServerTimingHeader() {
+	timings := ctx.timings.get()
+	if len(timings) == 0 {
+		return
+	}
+	ctx.Response().Header().Set(`Server-Timing`, formatServerTiming(timings))
+}
+
+// serverTimingResponseWriter sets the "Server-Timing" header right before
+// the headers are sent by engine/standard (it sends them on the first write)
+type serverTimingResponseWriter struct {
+	http.ResponseWriter
+	ctx *LoggerContext
+}
+
+func (w *serverTimingResponseWriter) WriteHeader(code int) {
+	w.ctx.setServerTimingHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *serverTimingResponseWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *serverTimingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (w *serverTimingResponseWriter) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// installServerTiming prepares the response to send the "Server-Timing" header, it
+// should be called before the handler
+func (ctx *LoggerContext) installServerTiming() {
+	if stdRes, ok := ctx.Response().(*standard.Response); ok {
+		stdRes.ResponseWriter = &serverTimingResponseWriter{
+			ResponseWriter: stdRes.ResponseWriter,
+			ctx:            ctx,
+		}
+	}
+}
+
+// finishServerTiming sets the "Server-Timing" header after the handler if it's
+// not too late. fasthttp sends the headers after the handler is finished,
+// so it's never too late there.
+func (ctx *LoggerContext) finishServerTiming() {
+	if _, ok := ctx.Response().(*fasthttp.Response); ok || !ctx.Response().Committed() {
+		ctx.setServerTimingHeader()
+	}
+}
@@ -0,0 +1,39 @@
+package echolog
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// useAfterReleaseModeT defines what to do if the logger of a released
+// LoggerContext is used. It's chosen by build tags:
+// * "echolog_debug": panic;
+// * "race": log an error with the stack trace;
+// * otherwise it's not checked.
+type useAfterReleaseModeT int
+
+const (
+	useAfterReleaseIgnore useAfterReleaseModeT = iota
+	useAfterReleaseLog
+	useAfterReleasePanic
+)
+
+// checkReleased reports usage of the logger after its LoggerContext is released
+func (ctxLogger *LoggerContextLogger) checkReleased() {
+	if useAfterReleaseMode == useAfterReleaseIgnore || ctxLogger.owner == nil {
+		return
+	}
+	if atomic.LoadUint64(&ctxLogger.owner.generation) == ctxLogger.generation {
+		return
+	}
+
+	message := fmt.Sprintf(`the logger of request "%v" is used after the request is released (use Detach() in goroutines which could outlive the request)`, ctxLogger.requestID)
+	if useAfterReleaseMode == useAfterReleasePanic {
+		panic(message)
+	}
+	ctxLogger.sink.WithFields(Fields{
+		`what`:        `use_after_release`,
+		`stack_trace`: string(debug.Stack()),
+	}).Write(SinkLevelError, message)
+}
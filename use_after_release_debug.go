@@ -0,0 +1,5 @@
+//go:build echolog_debug
+
+package echolog
+
+const useAfterReleaseMode = useAfterReleasePanic
@@ -0,0 +1,5 @@
+//go:build race && !echolog_debug
+
+package echolog
+
+const useAfterReleaseMode = useAfterReleaseLog
@@ -0,0 +1,27 @@
+module github.com/trafficstars/echolog/zapsink
+
+go 1.21
+
+require (
+	github.com/trafficstars/echolog v0.0.0
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.4 // indirect
+	github.com/klauspost/compress v1.8.2 // indirect
+	github.com/klauspost/cpuid v1.2.1 // indirect
+	github.com/labstack/gommon v0.3.0 // indirect
+	github.com/mattn/go-colorable v0.1.14 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/sirupsen/logrus v1.7.0 // indirect
+	github.com/trafficstars/echo v1.2.1-0.20210118175209-73964bf4328a // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.9.0 // indirect
+	github.com/valyala/fasttemplate v1.0.1 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
+	golang.org/x/sys v0.29.0 // indirect
+)
+
+replace github.com/trafficstars/echolog => ../
@@ -0,0 +1,71 @@
+// Package zapsink is the echolog.Sink adapter for zap
+package zapsink
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/trafficstars/echolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type zapSink struct {
+	logger *zap.Logger
+}
+
+// New returns a Sink writing to a zap logger (use Desugar() for a *zap.SugaredLogger).
+// Messages are filtered by the level of the core as well (see echolog.Sink).
+func New(logger *zap.Logger) echolog.Sink {
+	return zapSink{logger: logger}
+}
+
+func getZapLevel(level echolog.SinkLevel) zapcore.Level {
+	switch level {
+	case echolog.SinkLevelTrace, echolog.SinkLevelDebug: // zap has no trace level
+		return zapcore.DebugLevel
+	case echolog.SinkLevelInfo:
+		return zapcore.InfoLevel
+	case echolog.SinkLevelWarn:
+		return zapcore.WarnLevel
+	case echolog.SinkLevelFatal:
+		return zapcore.FatalLevel
+	case echolog.SinkLevelPanic:
+		return zapcore.PanicLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+func (s zapSink) WithFields(fields echolog.Fields) echolog.Sink {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	zapFields := make([]zap.Field, 0, len(fields))
+	for _, key := range keys {
+		zapFields = append(zapFields, zap.Any(key, fields[key]))
+	}
+	return zapSink{logger: s.logger.With(zapFields...)}
+}
+
+func (s zapSink) Write(level echolog.SinkLevel, message string) {
+	// The core is used directly because zap.Logger exits or panics on
+	// FatalLevel and PanicLevel
+	entry := zapcore.Entry{
+		LoggerName: s.logger.Name(),
+		Time:       time.Now(),
+		Level:      getZapLevel(level),
+		Message:    message,
+	}
+	if checkedEntry := s.logger.Core().Check(entry, nil); checkedEntry != nil {
+		checkedEntry.Write()
+	}
+}
+
+func (s zapSink) Exit(code int) {
+	_ = s.logger.Sync()
+	os.Exit(code)
+}
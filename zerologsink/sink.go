@@ -0,0 +1,44 @@
+// Package zerologsink is the echolog.Sink adapter for zerolog
+package zerologsink
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/trafficstars/echolog"
+)
+
+type zerologSink struct {
+	logger zerolog.Logger
+}
+
+// New returns a Sink writing to a zerolog logger. Messages are filtered by
+// the level of the logger and the global level as well (see echolog.Sink).
+func New(logger zerolog.Logger) echolog.Sink {
+	return zerologSink{logger: logger}
+}
+
+func getZerologLevel(level echolog.SinkLevel) zerolog.Level {
+	switch level {
+	case echolog.SinkLevelTrace:
+		return zerolog.TraceLevel
+	case echolog.SinkLevelDebug:
+		return zerolog.DebugLevel
+	case echolog.SinkLevelInfo:
+		return zerolog.InfoLevel
+	case echolog.SinkLevelWarn:
+		return zerolog.WarnLevel
+	case echolog.SinkLevelFatal:
+		return zerolog.FatalLevel
+	case echolog.SinkLevelPanic:
+		return zerolog.PanicLevel
+	}
+	return zerolog.ErrorLevel
+}
+
+func (s zerologSink) WithFields(fields echolog.Fields) echolog.Sink {
+	return zerologSink{logger: s.logger.With().Fields(map[string]interface{}(fields)).Logger()}
+}
+
+func (s zerologSink) Write(level echolog.SinkLevel, message string) {
+	// Unlike Fatal() and Panic(), WithLevel() neither exits nor panics
+	s.logger.WithLevel(getZerologLevel(level)).Msg(message)
+}